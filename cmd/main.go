@@ -1,164 +1,226 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"api-gateway/internal/apierror"
 	"api-gateway/internal/broker"
 	"api-gateway/internal/config"
-	"api-gateway/internal/handlers"
+	"api-gateway/internal/health"
+	"api-gateway/internal/logging"
+	"api-gateway/internal/metrics"
 	"api-gateway/internal/middleware"
+	"api-gateway/internal/proxy"
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/router"
+	"api-gateway/internal/tracing"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	logging.Setup(cfg)
+	cfg.LogSummary()
 
-	// Initialize RabbitMQ connection
-	rabbitClient, err := broker.NewRabbitMQClient(cfg.RabbitMQ.URL)
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Tracing and W3C trace context propagation
+	shutdownTracing, err := tracing.Init(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		fatal("Failed to initialize tracing", "error", err)
 	}
-	defer rabbitClient.Close()
-
-	// Declare queues
-	queues := []string{"user_actions", "notifications", "data_requests", "default_queue"}
-	for _, queue := range queues {
-		if err := rabbitClient.DeclareQueue(queue); err != nil {
-			log.Printf("Failed to declare queue %s: %v", queue, err)
-		} else {
-			log.Printf("Queue %s declared successfully", queue)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
 		}
-	}
-
-	// Create handler
-	handler := handlers.NewMessageHandler(rabbitClient)
+	}()
+
+	// Initialize RabbitMQ client; it connects in the background and keeps
+	// reconnecting, so the gateway starts even if the broker is not up yet.
+	// The configured exchanges, queues and bindings are declared on every
+	// connect.
+	rabbitClient := broker.NewRabbitMQClient(cfg.RabbitMQ)
+	defer func() {
+		rabbitClient.Close()
+		slog.Info("RabbitMQ connection closed")
+	}()
+
+	// Dependency checks behind /readyz
+	probes := health.NewRegistry(cfg.Health.CheckTimeout, cfg.Health.CacheTTL)
+	probes.Register("rabbitmq", true, rabbitClient)
 
 	// Setup router
-	router := gin.New()
-	router.RedirectTrailingSlash = false
+	engine := gin.New()
+	engine.RedirectTrailingSlash = false
+
+	// Only derive client IPs from headers set by trusted load balancers
+	if err := engine.SetTrustedProxies(cfg.Proxy.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", "error", err)
+	}
 
 	// Middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
+	engine.Use(middleware.RequestID())
+	if cfg.Logging.AccessLog {
+		engine.Use(logging.AccessLog(cfg.Logging))
+	}
+	engine.Use(tracing.Middleware())
+	if cfg.Metrics.Enabled {
+		// Before recovery so panics are counted as 5xx
+		engine.Use(metrics.Middleware(cfg.Routes))
+	}
+	engine.Use(middleware.Recovery())
+	corsMiddleware, err := middleware.NewCORSMiddleware(cfg.CORS, router.AllowedMethods(cfg.Routes))
+	if err != nil {
+		fatal("Invalid CORS configuration", "error", err)
+	}
+	engine.Use(corsMiddleware.Handler())
+	engine.Use(middleware.StripReservedHeaders(cfg.Proxy.ReservedHeaders))
 
-	// Health check
-	router.GET("/health", handler.HealthCheck)
+	// Liveness and readiness probes; /health is kept for existing checks
+	engine.GET("/livez", probes.LivezHandler)
+	engine.GET("/readyz", probes.ReadyzHandler)
+	engine.GET("/health", probes.ReadyzHandler)
 
 	// Proxy routes
-	proxy := NewReverseProxy(cfg)
+	reverseProxy, err := proxy.NewReverseProxy(cfg)
+	if err != nil {
+		fatal("Failed to initialize reverse proxy", "error", err)
+	}
+	reverseProxy.StartHealthChecks(ctx)
+	reverseProxy.OnBreakerStateChange(func(ev proxy.BreakerEvent) {
+		metrics.BreakerState.WithLabelValues(ev.Service).Set(float64(ev.To))
+	})
+	metrics.RegisterConcurrency(reverseProxy.ConcurrencyStats)
+	for _, name := range reverseProxy.Services() {
+		probes.Register("upstream:"+name, cfg.Health.RequireUpstreams, health.CheckerFunc(func(ctx context.Context) error {
+			return reverseProxy.CheckService(ctx, name)
+		}))
+	}
 
 	// JWT middleware
 	jwtMiddleware := middleware.NewJWTMiddleware(cfg.JWT.Secret)
 
-	// Auth service routes (public - no JWT)
-	authGroup := router.Group("/api/v1")
-	{
-		authGroup.Any("/auth/*path", proxy.proxyHandler("auth"))
-		authGroup.Any("/users/*path", proxy.proxyHandler("auth"))
-		authGroup.Any("/roles/*path", proxy.proxyHandler("auth"))
-		authGroup.Any("/permissions/*path", proxy.proxyHandler("auth"))
-	}
-
-	// Public read-only routes (no JWT required)
-	publicGroup := router.Group("/api/v1")
-	{
-		publicGroup.GET("/posts", proxy.proxyHandler("post"))
-		publicGroup.GET("/posts/*path", proxy.proxyHandler("post"))
-		publicGroup.GET("/comments", proxy.proxyHandler("comment"))
-		publicGroup.GET("/comments/*path", proxy.proxyHandler("comment"))
-	}
-
-	// Protected routes (require JWT for write operations)
-	protectedGroup := router.Group("/api/v1")
-	protectedGroup.Use(jwtMiddleware.Handler())
-	{
-		protectedGroup.POST("/posts", proxy.proxyHandler("post"))
-		protectedGroup.PATCH("/posts/:id", proxy.proxyHandler("post"))
-		protectedGroup.DELETE("/posts/:id", proxy.proxyHandler("post"))
-		protectedGroup.POST("/posts/:id/like", proxy.proxyHandler("post"))
-		protectedGroup.DELETE("/posts/:id/like", proxy.proxyHandler("post"))
-
-		protectedGroup.POST("/comments", proxy.proxyHandler("comment"))
-		protectedGroup.PATCH("/comments/*path", proxy.proxyHandler("comment"))
-		protectedGroup.DELETE("/comments/*path", proxy.proxyHandler("comment"))
-	}
+	// Rate limiting, shared across replicas through Redis when enabled
+	var rateLimit gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.Redis.Enabled {
+			redisClient, err := ratelimit.NewRedisClient(cfg.Redis)
+			if err != nil {
+				fatal("Failed to configure Redis", "error", err)
+			}
+			defer redisClient.Close()
+			probes.Register("redis", false, health.CheckerFunc(func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			}))
+			store = ratelimit.NewFallbackStore(
+				ratelimit.NewRedisStore(redisClient, "ratelimit:"), store, 5*time.Second)
+		}
 
-	// Start server
-	log.Printf("API Gateway starting on port %s", cfg.Port)
-	if err := router.Run(":" + cfg.Port); err != nil {
-		log.Fatal(err)
+		limiter, err := ratelimit.NewMiddleware(store, cfg.RateLimit)
+		if err != nil {
+			fatal("Invalid rate limit configuration", "error", err)
+		}
+		rateLimit = limiter.Handler()
 	}
-}
 
-// ReverseProxy handles routing to backend services
-type ReverseProxy struct {
-	config *config.Config
-}
+	// Routes from the configured route table
+	router.RegisterRoutes(engine, cfg.Routes, reverseProxy, jwtMiddleware.Handler(), rateLimit)
 
-func NewReverseProxy(cfg *config.Config) *ReverseProxy {
-	return &ReverseProxy{config: cfg}
-}
+	engine.NoRoute(func(c *gin.Context) {
+		apierror.Abort(c, apierror.CodeNotFound, "route not found")
+	})
 
-func (p *ReverseProxy) proxyHandler(serviceKey string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		svc, ok := p.config.Services[serviceKey]
-		if !ok || svc == nil || svc.URL == "" {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service not configured: " + serviceKey})
-			return
+	srv := &http.Server{
+		Addr:           ":" + cfg.Port,
+		Handler:        engine,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
+	servers := []*http.Server{srv}
+	errCh := make(chan error, 2)
+
+	// Metrics and operator endpoints on a separate listener so they are not
+	// exposed with the API
+	admin := gin.New()
+	admin.Use(gin.Recovery())
+	if cfg.Metrics.Enabled {
+		admin.GET(cfg.Metrics.Path, gin.WrapH(metrics.Handler()))
+	}
+	admin.GET("/admin/health", probes.ReportHandler)
+	admin.GET("/admin/upstreams", reverseProxy.UpstreamsHandler)
+	admin.GET("/admin/limits", reverseProxy.LimitsHandler)
+	adminSrv := &http.Server{
+		Addr:              ":" + cfg.Metrics.Port,
+		Handler:           admin,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout,
+	}
+	servers = append(servers, adminSrv)
+	go func() {
+		slog.Info("Metrics and admin listening", "port", cfg.Metrics.Port, "metrics_enabled", cfg.Metrics.Enabled)
+		if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("admin server: %w", err)
 		}
+	}()
 
-		targetURL, err := url.Parse(svc.URL)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid service URL"})
-			return
+	// Start server
+	go func() {
+		slog.Info("API Gateway starting", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
 		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining requests", "grace_period", cfg.Server.ShutdownTimeout.String())
+	case err := <-errCh:
+		slog.Error("Server failed, shutting down", "error", err)
+	case err := <-rabbitClient.Failed():
+		slog.Error("RabbitMQ topology rejected, shutting down", "error", err)
+	}
+	stop()
+	probes.SetDraining()
+
+	// Keep serving while load balancers see /readyz fail and stop routing
+	// new requests here
+	if cfg.Server.DrainDelay > 0 {
+		slog.Info("Waiting for readiness to propagate", "delay", cfg.Server.DrainDelay.String())
+		time.Sleep(cfg.Server.DrainDelay)
+	}
 
-		// Get the full path including the route prefix
-		fullPath := c.Request.URL.Path
-
-		log.Printf("Proxying %s %s to %s", c.Request.Method, fullPath, svc.URL+fullPath)
-
-		proxy := httputil.NewSingleHostReverseProxy(targetURL)
-		proxy.Director = func(req *http.Request) {
-			req.URL.Scheme = targetURL.Scheme
-			req.URL.Host = targetURL.Host
-			req.URL.Path = fullPath
-			req.URL.RawQuery = c.Request.URL.RawQuery
-			req.Host = targetURL.Host
-			req.Header = c.Request.Header.Clone()
-			if _, exists := req.Header["User-Agent"]; !exists {
-				req.Header["User-Agent"] = []string{"api-gateway"}
-			}
-			if userID, exists := c.Get("x_user_id"); exists && userID != "" {
-				req.Header.Set("X-User-ID", userID.(string))
-			}
-			if username, exists := c.Get("x_username"); exists && username != "" {
-				req.Header.Set("X-Username", username.(string))
-			}
+	// Stop accepting connections and wait for in-flight requests, including
+	// proxied calls and publishes made by handlers, up to the grace period.
+	// Deferred cleanup then closes Redis and RabbitMQ and flushes traces.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Graceful shutdown incomplete, closing remaining connections", "addr", s.Addr, "error", err)
+			s.Close()
 		}
-
-		proxy.ServeHTTP(c.Writer, c.Request)
 	}
+	slog.Info("HTTP servers stopped")
 }
 
-// CORS middleware for mobile clients
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	}
+// fatal logs at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,99 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/models"
+	"api-gateway/internal/requestid"
+)
+
+// Error codes returned in models.ErrorDetail.Code
+const (
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeNotFound             = "not_found"
+	CodeInternal             = "internal_error"
+	CodeServiceNotConfigured = "service_not_configured"
+	CodeNoUpstream           = "no_upstream_available"
+	CodeUpstreamTimeout      = "upstream_timeout"
+	CodeConnectionRefused    = "upstream_connection_refused"
+	CodeBadGateway           = "bad_gateway"
+	CodeCircuitOpen          = "circuit_open"
+	CodeRateLimited          = "rate_limited"
+	CodeServiceUnavailable   = "service_unavailable"
+	CodeOverloaded           = "overloaded"
+	CodeMessageUnroutable    = "message_unroutable"
+	CodePublishUnconfirmed   = "publish_unconfirmed"
+)
+
+type codeInfo struct {
+	status    int
+	retryable bool
+}
+
+var codes = map[string]codeInfo{
+	CodeBadRequest:           {http.StatusBadRequest, false},
+	CodeUnauthorized:         {http.StatusUnauthorized, false},
+	CodeNotFound:             {http.StatusNotFound, false},
+	CodeInternal:             {http.StatusInternalServerError, false},
+	CodeServiceNotConfigured: {http.StatusServiceUnavailable, false},
+	CodeNoUpstream:           {http.StatusServiceUnavailable, true},
+	CodeUpstreamTimeout:      {http.StatusGatewayTimeout, true},
+	CodeConnectionRefused:    {http.StatusBadGateway, true},
+	CodeBadGateway:           {http.StatusBadGateway, true},
+	CodeCircuitOpen:          {http.StatusServiceUnavailable, true},
+	CodeRateLimited:          {http.StatusTooManyRequests, true},
+	CodeServiceUnavailable:   {http.StatusServiceUnavailable, true},
+	CodeOverloaded:           {http.StatusServiceUnavailable, true},
+	CodeMessageUnroutable:    {http.StatusInternalServerError, false},
+	CodePublishUnconfirmed:   {http.StatusGatewayTimeout, true},
+}
+
+// Status returns the HTTP status used for an error code
+func Status(code string) int {
+	if info, ok := codes[code]; ok {
+		return info.status
+	}
+	return http.StatusInternalServerError
+}
+
+// New builds the error envelope for code
+func New(code, message, requestID, service string) models.ErrorResponse {
+	return models.ErrorResponse{
+		Error: models.ErrorDetail{
+			Code:      code,
+			Message:   message,
+			RequestID: requestID,
+			Service:   service,
+			Retryable: codes[code].retryable,
+		},
+	}
+}
+
+// Abort writes the error envelope for code and aborts the gin chain
+func Abort(c *gin.Context, code, message string) {
+	AbortService(c, code, message, "")
+}
+
+// AbortService is Abort for errors attributed to an upstream service
+func AbortService(c *gin.Context, code, message, service string) {
+	c.AbortWithStatusJSON(Status(code), New(code, message, requestID(c.Request), service))
+}
+
+// Write writes the error envelope outside of a gin handler, e.g. from
+// httputil.ReverseProxy's ErrorHandler
+func Write(w http.ResponseWriter, r *http.Request, code, message, service string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(Status(code))
+	json.NewEncoder(w).Encode(New(code, message, requestID(r), service))
+}
+
+func requestID(r *http.Request) string {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(requestid.Header)
+}
@@ -0,0 +1,215 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrNacked means the broker refused responsibility for the message
+	ErrNacked = errors.New("rabbitmq: message nacked by broker")
+	// ErrUnconfirmed means no confirm arrived in time; the message may or
+	// may not have been delivered
+	ErrUnconfirmed = errors.New("rabbitmq: publish not confirmed in time")
+	// errChannelClosed fails publishes pending when the channel goes away
+	errChannelClosed = errors.New("rabbitmq: channel closed before confirm")
+)
+
+// ReturnError reports a mandatory message the broker could not route
+type ReturnError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *ReturnError) Error() string {
+	return fmt.Sprintf("rabbitmq: message returned (%d %s) for exchange %q routing key %q",
+		e.ReplyCode, e.ReplyText, e.Exchange, e.RoutingKey)
+}
+
+// publishChannel wraps a channel in confirm mode and matches broker
+// confirms and returns to the publishes waiting on them
+type publishChannel struct {
+	ch       *amqp.Channel
+	confirms bool
+
+	// publishMu serialises publishes so delivery tags follow publish order
+	publishMu sync.Mutex
+	nextTag   uint64
+
+	mu      sync.Mutex
+	pending map[uint64]*pendingPublish
+	byID    map[string]*pendingPublish
+	closed  bool
+}
+
+type pendingPublish struct {
+	id       string
+	returned *ReturnError
+	done     chan error
+}
+
+func newPublishChannel(ch *amqp.Channel, confirms bool) (*publishChannel, error) {
+	pc := &publishChannel{
+		ch:       ch,
+		confirms: confirms,
+		pending:  make(map[uint64]*pendingPublish),
+		byID:     make(map[string]*pendingPublish),
+	}
+
+	// Returns are received unbuffered, so the broker's basic.return is fully
+	// handled before the basic.ack that follows it is dispatched
+	returns := ch.NotifyReturn(make(chan amqp.Return))
+	var acks chan amqp.Confirmation
+	if confirms {
+		if err := ch.Confirm(false); err != nil {
+			return nil, fmt.Errorf("enable publisher confirms: %w", err)
+		}
+		acks = ch.NotifyPublish(make(chan amqp.Confirmation, 128))
+	}
+
+	go pc.listen(acks, returns)
+	return pc, nil
+}
+
+func (pc *publishChannel) listen(acks <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for acks != nil || returns != nil {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			pc.handleReturn(ret)
+		case conf, ok := <-acks:
+			if !ok {
+				acks = nil
+				continue
+			}
+			pc.handleConfirm(conf)
+		}
+	}
+	pc.failPending()
+}
+
+func (pc *publishChannel) handleReturn(ret amqp.Return) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if p, ok := pc.byID[ret.MessageId]; ok {
+		p.returned = &ReturnError{
+			Exchange:   ret.Exchange,
+			RoutingKey: ret.RoutingKey,
+			ReplyCode:  ret.ReplyCode,
+			ReplyText:  ret.ReplyText,
+		}
+	}
+}
+
+func (pc *publishChannel) handleConfirm(conf amqp.Confirmation) {
+	pc.mu.Lock()
+	p, ok := pc.pending[conf.DeliveryTag]
+	if ok {
+		delete(pc.pending, conf.DeliveryTag)
+		delete(pc.byID, p.id)
+	}
+	pc.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	switch {
+	case !conf.Ack:
+		p.done <- ErrNacked
+	case p.returned != nil:
+		// Unroutable messages are still acked, the return tells them apart
+		p.done <- p.returned
+	default:
+		p.done <- nil
+	}
+}
+
+func (pc *publishChannel) failPending() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.closed = true
+	for tag, p := range pc.pending {
+		if p.returned != nil {
+			p.done <- p.returned
+		} else {
+			p.done <- errChannelClosed
+		}
+		delete(pc.pending, tag)
+	}
+	pc.byID = make(map[string]*pendingPublish)
+}
+
+// isClosed reports whether the underlying channel has gone away
+func (pc *publishChannel) isClosed() bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.closed
+}
+
+// send publishes msg. In confirm mode the returned pending publish is
+// resolved by the broker's confirm or return; it is nil otherwise.
+func (pc *publishChannel) send(exchange, key string, mandatory bool, msg amqp.Publishing) (*pendingPublish, error) {
+	if msg.MessageId == "" {
+		msg.MessageId = uuid.NewString()
+	}
+
+	if !pc.confirms {
+		return nil, pc.ch.Publish(exchange, key, mandatory, false, msg)
+	}
+
+	p := &pendingPublish{id: msg.MessageId, done: make(chan error, 1)}
+
+	pc.publishMu.Lock()
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		pc.publishMu.Unlock()
+		return nil, errChannelClosed
+	}
+	tag := pc.nextTag + 1
+	pc.pending[tag] = p
+	pc.byID[p.id] = p
+	pc.mu.Unlock()
+
+	err := pc.ch.Publish(exchange, key, mandatory, false, msg)
+	if err == nil {
+		pc.nextTag = tag
+	}
+	pc.publishMu.Unlock()
+
+	if err != nil {
+		pc.mu.Lock()
+		delete(pc.pending, tag)
+		delete(pc.byID, p.id)
+		pc.mu.Unlock()
+		return nil, err
+	}
+	return p, nil
+}
+
+// wait blocks until the broker confirms or returns the message, or ctx is done
+func (p *pendingPublish) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case err := <-p.done:
+		return err
+	case <-ctx.Done():
+		// The confirm may still arrive; done is buffered so the listener
+		// never blocks on an abandoned publish
+		return ErrUnconfirmed
+	}
+}
@@ -1 +1,80 @@
 package broker
+
+import (
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// consumer forwards deliveries from the current channel to a stable output
+// channel, so callers keep receiving across reconnects
+type consumer struct {
+	queue string
+	out   chan amqp.Delivery
+
+	wg       sync.WaitGroup
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// ConsumeMessages consumes queue with manual acknowledgement. The returned
+// channel survives reconnects and is closed after Close. Deliveries must be
+// acknowledged before a reconnect, their delivery tags are per channel.
+func (c *RabbitMQClient) ConsumeMessages(queue string) (<-chan amqp.Delivery, error) {
+	cons := &consumer{
+		queue: queue,
+		out:   make(chan amqp.Delivery),
+		done:  make(chan struct{}),
+	}
+
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+
+	if ch, err := c.currentChannel(); err == nil {
+		if err := cons.start(ch); err != nil {
+			return nil, err
+		}
+	}
+	c.consumers = append(c.consumers, cons)
+
+	return cons.out, nil
+}
+
+func (cons *consumer) start(ch *amqp.Channel) error {
+	msgs, err := ch.Consume(
+		cons.queue,
+		"",    // consumer
+		false, // auto-ack (false for manual acknowledgment)
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	cons.wg.Add(1)
+	go func() {
+		defer cons.wg.Done()
+		// msgs is closed when the channel goes away
+		for d := range msgs {
+			select {
+			case cons.out <- d:
+			case <-cons.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (cons *consumer) stop() {
+	cons.stopOnce.Do(func() {
+		close(cons.done)
+		go func() {
+			cons.wg.Wait()
+			close(cons.out)
+		}()
+	})
+}
@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// defaultPoolSize bounds the pool when the broker allows unlimited channels
+const defaultPoolSize = 64
+
+// channelPool lends publishing channels of one connection to concurrent
+// publishers. Channels are opened on demand up to size and returned to the
+// pool as soon as the message is sent, so a channel can carry several
+// publishes awaiting confirms. Closed channels are dropped and replaced.
+type channelPool struct {
+	conn     *amqp.Connection
+	confirms bool
+
+	idle  chan *publishChannel
+	slots chan struct{}
+}
+
+// newChannelPool sizes the pool below channelMax, keeping one channel of
+// the connection for consumers
+func newChannelPool(conn *amqp.Connection, channelMax int, confirms bool) *channelPool {
+	size := channelMax - 1
+	if channelMax <= 0 {
+		size = defaultPoolSize
+	}
+	size = max(size, 1)
+
+	return &channelPool{
+		conn:     conn,
+		confirms: confirms,
+		idle:     make(chan *publishChannel, size),
+		slots:    make(chan struct{}, size),
+	}
+}
+
+// get returns an idle channel, opens a new one while below the bound, or
+// waits for one to be returned until ctx is done
+func (p *channelPool) get(ctx context.Context) (*publishChannel, error) {
+	for {
+		var pc *publishChannel
+		select {
+		case pc = <-p.idle:
+		default:
+			select {
+			case pc = <-p.idle:
+			case p.slots <- struct{}{}:
+				return p.open()
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if !pc.isClosed() {
+			return pc, nil
+		}
+		// Free the slot of the dead channel and try again
+		<-p.slots
+	}
+}
+
+func (p *channelPool) open() (*publishChannel, error) {
+	ch, err := p.conn.Channel()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	pc, err := newPublishChannel(ch, p.confirms)
+	if err != nil {
+		ch.Close()
+		<-p.slots
+		return nil, err
+	}
+	return pc, nil
+}
+
+// put returns a channel to the pool
+func (p *channelPool) put(pc *publishChannel) {
+	if pc.isClosed() {
+		<-p.slots
+		return
+	}
+	p.idle <- pc
+}
@@ -1 +1,136 @@
 package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"api-gateway/internal/metrics"
+	"api-gateway/internal/tracing"
+)
+
+// Message is published to Exchange with RoutingKey. With an empty Exchange
+// it goes through the default exchange, where RoutingKey is the queue name.
+// Headers are sent as message headers, e.g. for headers exchanges.
+type Message struct {
+	Exchange   string
+	RoutingKey string
+	Headers    map[string]interface{}
+	Body       interface{}
+}
+
+// destination names the exchange, or the queue for the default exchange
+func (m Message) destination() string {
+	if m.Exchange == "" {
+		return m.RoutingKey
+	}
+	return m.Exchange
+}
+
+// PublishMessage publishes body as JSON to queue through the default exchange
+func (c *RabbitMQClient) PublishMessage(ctx context.Context, queue string, body interface{}) error {
+	return c.Publish(ctx, Message{RoutingKey: queue, Body: body})
+}
+
+// Publish publishes msg.Body as JSON. A producer span is started from ctx
+// and its trace context is propagated in the message headers. While the
+// client is reconnecting it fails fast with ErrNotConnected. Concurrent
+// publishes are spread over a bounded pool of channels.
+//
+// With publisher confirms enabled it waits up to ConfirmTimeout for the
+// broker to take responsibility for the message and returns ErrNacked,
+// ErrUnconfirmed or a *ReturnError when the message was not routed to any
+// queue (mandatory publishing).
+func (c *RabbitMQClient) Publish(ctx context.Context, msg Message) error {
+	dest := msg.destination()
+	ctx, span := tracing.Tracer().Start(ctx, "publish "+dest,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(dest),
+			semconv.MessagingRabbitmqDestinationRoutingKey(msg.RoutingKey),
+		),
+	)
+	defer span.End()
+
+	err := c.publish(ctx, msg)
+	metrics.Publish(dest, publishResult(err))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (c *RabbitMQClient) publish(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(msg.Body)
+	if err != nil {
+		return err
+	}
+
+	pool, err := c.currentPool()
+	if err != nil {
+		return err
+	}
+
+	headers := amqp.Table{}
+	for k, v := range table(msg.Headers) {
+		headers[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, tracing.AMQPCarrier(headers))
+
+	// The timeout also bounds waiting for a free channel
+	if c.cfg.ConfirmTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.ConfirmTimeout)
+		defer cancel()
+	}
+
+	pc, err := pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := pc.send(
+		msg.Exchange,
+		msg.RoutingKey,
+		c.cfg.Mandatory,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  "application/json",
+			Body:         data,
+			DeliveryMode: amqp.Persistent, // make messages persistent
+		})
+	// Hand the channel back before waiting so other publishes can use it
+	// while this confirm is outstanding
+	pool.put(pc)
+	if err != nil {
+		return err
+	}
+	return pending.wait(ctx)
+}
+
+// publishResult classifies a publish error for metrics
+func publishResult(err error) string {
+	var returned *ReturnError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.As(err, &returned):
+		return "returned"
+	case errors.Is(err, ErrNacked):
+		return "nacked"
+	case errors.Is(err, ErrUnconfirmed):
+		return "unconfirmed"
+	case errors.Is(err, ErrNotConnected):
+		return "not_connected"
+	default:
+		return "failure"
+	}
+}
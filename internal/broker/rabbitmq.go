@@ -1,26 +1,180 @@
 package broker
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
+
+	"api-gateway/internal/config"
+)
+
+// ErrNotConnected is returned while the client is (re)connecting to the broker
+var ErrNotConnected = errors.New("rabbitmq: not connected")
+
+// ConnectionState describes the client's connection to the broker
+type ConnectionState int
+
+const (
+	StateConnecting ConnectionState = iota
+	StateConnected
+	StateReconnecting
+	StateClosed
+	// StateFailed means the broker rejected the topology and the client
+	// stopped reconnecting
+	StateFailed
 )
 
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	case StateFailed:
+		return "failed"
+	default:
+		return "connecting"
+	}
+}
+
+// RabbitMQClient keeps a connection and channel to the broker, plus a pool
+// of channels for concurrent publishing. On every connect it declares the
+// configured exchanges, queues and bindings; when the connection or the
+// topology channel is closed it reconnects with exponential backoff,
+// declares the topology again and resumes consumers. The broker does not
+// have to be up when the client is created.
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	config  *amqp.Config
+	url    string
+	config amqp.Config
+	cfg    *config.RabbitMQConfig
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	pool      *channelPool
+	state     ConnectionState
+	lastErr   error
+	connected chan struct{} // closed while connected, replaced on disconnect
+
+	// Topology and consumers replayed after every reconnect
+	topologyMu sync.Mutex
+	topology   topology
+	consumers  []*consumer
+
+	done      chan struct{}
+	failed    chan error
+	closeOnce sync.Once
+}
+
+func NewRabbitMQClient(cfg *config.RabbitMQConfig) *RabbitMQClient {
+	c := &RabbitMQClient{
+		url: cfg.URL,
+		config: amqp.Config{
+			Heartbeat:  time.Duration(cfg.Heartbeat) * time.Second,
+			ChannelMax: cfg.ChannelMax,
+			FrameSize:  cfg.FrameMax,
+			Dial:       amqp.DefaultDial(cfg.ConnectionTimeout),
+		},
+		cfg:       cfg,
+		topology:  newTopology(cfg),
+		connected: make(chan struct{}),
+		done:      make(chan struct{}),
+		failed:    make(chan error, 1),
+	}
+
+	go c.run()
+	return c
+}
+
+// run connects and waits for the connection or channel to close, then
+// reconnects until Close is called
+func (c *RabbitMQClient) run() {
+	attempt := 0
+	for {
+		conn, ch, pool, err := c.connect()
+		if err != nil {
+			c.setState(StateReconnecting, err)
+			wait := c.backoff(attempt)
+			attempt++
+			slog.Warn("RabbitMQ connection failed, retrying", "error", err, "retry_in", wait.String())
+			if !c.sleep(wait) {
+				return
+			}
+			continue
+		}
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chanClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		c.mu.Lock()
+		if c.state == StateClosed {
+			// Close was called while dialing
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conn, c.channel, c.pool = conn, ch, pool
+		c.mu.Unlock()
+
+		if err := c.restore(ch); err != nil {
+			conn.Close()
+			if errors.Is(err, ErrTopologyConflict) {
+				slog.Error("RabbitMQ rejected the configured topology", "error", err)
+				c.setState(StateFailed, err)
+				c.failed <- err
+				return
+			}
+			c.setState(StateReconnecting, err)
+			// Back off like a failed dial, the broker would refuse again
+			wait := c.backoff(attempt)
+			attempt++
+			slog.Error("Failed to restore RabbitMQ topology, retrying", "error", err, "retry_in", wait.String())
+			if !c.sleep(wait) {
+				return
+			}
+			continue
+		}
+		attempt = 0
+		slog.Info("RabbitMQ connected")
+
+		var reason *amqp.Error
+		select {
+		case <-c.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-chanClosed:
+			// A channel closed by a broker error leaves the connection up,
+			// reconnect both to start from a clean state
+			conn.Close()
+		}
+
+		err = errors.New("connection closed")
+		if reason != nil {
+			err = reason
+		}
+		c.setState(StateReconnecting, err)
+		slog.Warn("RabbitMQ connection lost, reconnecting", "error", err)
+	}
 }
 
-func NewRabbitMQClient(url string) (*RabbitMQClient, error) {
-	conn, err := amqp.Dial(url)
+func (c *RabbitMQClient) connect() (*amqp.Connection, *amqp.Channel, *channelPool, error) {
+	conn, err := amqp.DialConfig(c.url, c.config)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		conn.Close()
+		return nil, nil, nil, err
 	}
 
 	// Configure QoS
@@ -30,65 +184,179 @@ func NewRabbitMQClient(url string) (*RabbitMQClient, error) {
 		false, // global
 	)
 	if err != nil {
-		return nil, err
+		conn.Close()
+		return nil, nil, nil, err
 	}
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: ch,
-	}, nil
-}
+	// Publishing channels are opened lazily by the pool
+	pool := newChannelPool(conn, c.cfg.ChannelMax, c.cfg.PublisherConfirms)
 
-func (c *RabbitMQClient) DeclareQueue(name string) error {
-	_, err := c.channel.QueueDeclare(
-		name,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	return err
+	return conn, ch, pool, nil
 }
 
-func (c *RabbitMQClient) PublishMessage(queue string, body interface{}) error {
-	data, err := json.Marshal(body)
-	if err != nil {
+// restore declares the topology and restarts consumers on a new channel,
+// then marks the client connected. Holding topologyMu until then
+// keeps DeclareQueue and ConsumeMessages from slipping between the two.
+func (c *RabbitMQClient) restore(ch *amqp.Channel) error {
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+
+	if err := c.topology.apply(ch); err != nil {
 		return err
 	}
+	for _, cons := range c.consumers {
+		if err := cons.start(ch); err != nil {
+			return fmt.Errorf("consume %s: %w", cons.queue, err)
+		}
+	}
+	c.setState(StateConnected, nil)
+	return nil
+}
 
-	err = c.channel.Publish(
-		"",    // exchange
-		queue, // routing key
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         data,
-			DeliveryMode: amqp.Persistent, // make messages persistent
-		})
-
-	return err
-}
-
-func (c *RabbitMQClient) ConsumeMessages(queue string) (<-chan amqp.Delivery, error) {
-	msgs, err := c.channel.Consume(
-		queue,
-		"",    // consumer
-		false, // auto-ack (false for manual acknowledgment)
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,
-	)
-	return msgs, err
+// sleep waits for d and reports false when Close was called meanwhile
+func (c *RabbitMQClient) sleep(d time.Duration) bool {
+	select {
+	case <-c.done:
+		return false
+	case <-time.After(d):
+		return true
+	}
 }
 
-func (c *RabbitMQClient) Close() {
-	if c.channel != nil {
-		c.channel.Close()
+// backoff returns the exponential reconnect delay with jitter
+func (c *RabbitMQClient) backoff(attempt int) time.Duration {
+	d := c.cfg.ReconnectMinBackoff
+	for i := 0; i < attempt && d < c.cfg.ReconnectMaxBackoff; i++ {
+		d *= 2
 	}
-	if c.conn != nil {
-		c.conn.Close()
+	d = min(d, c.cfg.ReconnectMaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+func (c *RabbitMQClient) setState(state ConnectionState, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == StateClosed {
+		return
 	}
+	c.state = state
+	c.lastErr = err
+
+	select {
+	case <-c.connected:
+		if state != StateConnected {
+			c.connected = make(chan struct{})
+		}
+	default:
+		if state == StateConnected {
+			close(c.connected)
+		}
+	}
+}
+
+// State returns the current connection state
+func (c *RabbitMQClient) State() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// WaitConnected blocks until the client is connected or ctx is done
+func (c *RabbitMQClient) WaitConnected(ctx context.Context) error {
+	c.mu.RLock()
+	connected := c.connected
+	c.mu.RUnlock()
+
+	select {
+	case <-connected:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check reports whether the client is connected, for readiness probes
+func (c *RabbitMQClient) Check(ctx context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.state == StateConnected {
+		return nil
+	}
+	if c.lastErr != nil {
+		return fmt.Errorf("%s: %w", c.state, c.lastErr)
+	}
+	return errors.New(c.state.String())
+}
+
+// currentPool returns the publishing channel pool of the open connection or ErrNotConnected
+func (c *RabbitMQClient) currentPool() (*channelPool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != StateConnected {
+		return nil, ErrNotConnected
+	}
+	return c.pool, nil
+}
+
+// currentChannel returns the open channel or ErrNotConnected
+func (c *RabbitMQClient) currentChannel() (*amqp.Channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != StateConnected {
+		return nil, ErrNotConnected
+	}
+	return c.channel, nil
+}
+
+// DeclareQueue declares a durable queue in addition to the configured
+// topology and records it so it is declared again after every reconnect.
+// While disconnected the queue is only recorded and declared once the
+// connection is up.
+func (c *RabbitMQClient) DeclareQueue(name string) error {
+	q := config.QueueConfig{Name: name, Durable: true}
+
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+	c.topology.queues = append(c.topology.queues, q)
+
+	ch, err := c.currentChannel()
+	if err != nil {
+		return nil
+	}
+	if err := declareQueue(ch, q); err != nil {
+		return topologyError(err)
+	}
+	return nil
+}
+
+// Failed receives the error when the client gives up because the broker
+// rejected the configured topology
+func (c *RabbitMQClient) Failed() <-chan error {
+	return c.failed
+}
+
+func (c *RabbitMQClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		c.state = StateClosed
+		conn, ch := c.conn, c.channel
+		c.mu.Unlock()
+
+		if ch != nil {
+			ch.Close()
+		}
+		if conn != nil {
+			conn.Close()
+		}
+
+		c.topologyMu.Lock()
+		for _, cons := range c.consumers {
+			cons.stop()
+		}
+		c.topologyMu.Unlock()
+	})
 }
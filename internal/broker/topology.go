@@ -0,0 +1,126 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/streadway/amqp"
+
+	"api-gateway/internal/config"
+)
+
+// ErrTopologyConflict means the broker rejected a declaration because an
+// exchange or queue already exists with different settings. Reconnecting
+// cannot fix it, so the client stops and reports it through Failed.
+var ErrTopologyConflict = errors.New("rabbitmq: topology conflicts with existing declarations")
+
+// topology is the set of exchanges, queues and bindings the client declares
+// after every (re)connect
+type topology struct {
+	exchanges []config.ExchangeConfig
+	queues    []config.QueueConfig
+	bindings  []config.BindingConfig
+}
+
+func newTopology(cfg *config.RabbitMQConfig) topology {
+	return topology{
+		exchanges: append([]config.ExchangeConfig(nil), cfg.Exchanges...),
+		queues:    append([]config.QueueConfig(nil), cfg.Queues...),
+		bindings:  append([]config.BindingConfig(nil), cfg.Bindings...),
+	}
+}
+
+// apply declares exchanges first, then queues, then the bindings between them
+func (t *topology) apply(ch *amqp.Channel) error {
+	for _, ex := range t.exchanges {
+		if err := declareExchange(ch, ex); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", ex.Name, topologyError(err))
+		}
+	}
+	for _, q := range t.queues {
+		if err := declareQueue(ch, q); err != nil {
+			return fmt.Errorf("declare queue %s: %w", q.Name, topologyError(err))
+		}
+	}
+	for _, b := range t.bindings {
+		if err := bind(ch, b); err != nil {
+			return fmt.Errorf("bind %s to %s: %w", b.Destination, b.Source, topologyError(err))
+		}
+	}
+	return nil
+}
+
+func declareExchange(ch *amqp.Channel, ex config.ExchangeConfig) error {
+	return ch.ExchangeDeclare(
+		ex.Name,
+		ex.Type,
+		ex.Durable,
+		ex.AutoDelete,
+		ex.Internal,
+		false, // no-wait
+		table(ex.Arguments),
+	)
+}
+
+func declareQueue(ch *amqp.Channel, q config.QueueConfig) error {
+	_, err := ch.QueueDeclare(
+		q.Name,
+		q.Durable,
+		q.AutoDelete,
+		q.Exclusive,
+		false, // no-wait
+		table(q.Arguments),
+	)
+	return err
+}
+
+func bind(ch *amqp.Channel, b config.BindingConfig) error {
+	if b.DestinationType == "exchange" {
+		return ch.ExchangeBind(b.Destination, b.RoutingKey, b.Source, false, table(b.Arguments))
+	}
+	return ch.QueueBind(b.Destination, b.RoutingKey, b.Source, false, table(b.Arguments))
+}
+
+// topologyError marks PRECONDITION_FAILED replies as ErrTopologyConflict
+func topologyError(err error) error {
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return fmt.Errorf("%w: %s", ErrTopologyConflict, amqpErr.Reason)
+	}
+	return err
+}
+
+// table converts JSON arguments to an AMQP table. JSON numbers decode as
+// float64, but the broker expects integers for arguments such as
+// x-message-ttl and x-max-length, so whole numbers are sent as int64.
+func table(args map[string]interface{}) amqp.Table {
+	if len(args) == 0 {
+		return nil
+	}
+	t := make(amqp.Table, len(args))
+	for k, v := range args {
+		t[k] = tableValue(v)
+	}
+	return t
+}
+
+func tableValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case map[string]interface{}:
+		return table(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = tableValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
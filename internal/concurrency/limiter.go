@@ -0,0 +1,125 @@
+package concurrency
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority orders traffic for load shedding
+type Priority int
+
+const (
+	// PriorityLow is shed first, e.g. anonymous reads
+	PriorityLow Priority = iota
+	PriorityNormal
+)
+
+// Options configures a Limiter. With Adaptive set the limit moves between
+// MinLimit and MaxLimit using AIMD on observed latency; otherwise it is
+// fixed at MaxLimit.
+type Options struct {
+	MaxLimit         int
+	MinLimit         int
+	Adaptive         bool
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+	// LowPriorityShare is the fraction of the limit low priority requests may use
+	LowPriorityShare float64
+}
+
+// Limiter caps in-flight requests. Low priority requests are rejected once
+// in-flight requests reach LowPriorityShare of the limit, keeping headroom
+// for normal traffic when the limit shrinks.
+type Limiter struct {
+	name string
+	opts Options
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	shed     map[Priority]int64
+}
+
+// Stats is a point-in-time view of a limiter
+type Stats struct {
+	Name       string `json:"name"`
+	Limit      int    `json:"limit"`
+	InFlight   int    `json:"in_flight"`
+	ShedLow    int64  `json:"shed_low_priority"`
+	ShedNormal int64  `json:"shed_normal_priority"`
+}
+
+func NewLimiter(name string, opts Options) *Limiter {
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit < opts.MinLimit {
+		opts.MaxLimit = opts.MinLimit
+	}
+	return &Limiter{
+		name:  name,
+		opts:  opts,
+		limit: float64(opts.MaxLimit),
+		shed:  make(map[Priority]int64),
+	}
+}
+
+// Acquire admits a request or reports that it must be shed. When admitted,
+// release must be called with the request latency and whether it failed.
+func (l *Limiter) Acquire(priority Priority) (release func(latency time.Duration, failed bool), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := l.limit
+	if priority == PriorityLow {
+		capacity *= l.opts.LowPriorityShare
+	}
+	if float64(l.inFlight) >= capacity {
+		l.shed[priority]++
+		return nil, false
+	}
+
+	l.inFlight++
+	var once sync.Once
+	return func(latency time.Duration, failed bool) {
+		once.Do(func() { l.release(latency, failed) })
+	}, true
+}
+
+func (l *Limiter) release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inFlight := l.inFlight
+	l.inFlight--
+	if !l.opts.Adaptive {
+		return
+	}
+
+	switch {
+	case failed || latency > l.opts.LatencyThreshold:
+		// Multiplicative decrease when the backend slows down
+		l.limit *= l.opts.BackoffRatio
+		if l.limit < float64(l.opts.MinLimit) {
+			l.limit = float64(l.opts.MinLimit)
+		}
+	case float64(inFlight)*2 >= l.limit:
+		// Additive increase only while the limit is actually being used
+		l.limit++
+		if l.limit > float64(l.opts.MaxLimit) {
+			l.limit = float64(l.opts.MaxLimit)
+		}
+	}
+}
+
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Stats{
+		Name:       l.name,
+		Limit:      int(l.limit),
+		InFlight:   l.inFlight,
+		ShedLow:    l.shed[PriorityLow],
+		ShedNormal: l.shed[PriorityNormal],
+	}
+}
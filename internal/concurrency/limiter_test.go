@@ -0,0 +1,119 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterShedsLowPriorityFirst(t *testing.T) {
+	l := NewLimiter("test", Options{MaxLimit: 4, LowPriorityShare: 0.5})
+
+	var releases []func(time.Duration, bool)
+	acquire := func(p Priority) bool {
+		release, ok := l.Acquire(p)
+		if ok {
+			releases = append(releases, release)
+		}
+		return ok
+	}
+
+	// Low priority may use half the limit, normal traffic the rest
+	for i, want := range []bool{true, true, false} {
+		if got := acquire(PriorityLow); got != want {
+			t.Fatalf("low priority request %d admitted = %v, want %v", i, got, want)
+		}
+	}
+	for i, want := range []bool{true, true, false} {
+		if got := acquire(PriorityNormal); got != want {
+			t.Fatalf("normal priority request %d admitted = %v, want %v", i, got, want)
+		}
+	}
+
+	st := l.Stats()
+	if st.InFlight != 4 || st.ShedLow != 1 || st.ShedNormal != 1 {
+		t.Fatalf("stats = %+v, want 4 in flight, 1 low and 1 normal shed", st)
+	}
+
+	for _, release := range releases {
+		release(time.Millisecond, false)
+		// Releasing twice must not free a second slot
+		release(time.Millisecond, false)
+	}
+	if st := l.Stats(); st.InFlight != 0 {
+		t.Fatalf("in flight after release = %d, want 0", st.InFlight)
+	}
+}
+
+func TestLimiterAdaptsToLatency(t *testing.T) {
+	l := NewLimiter("test", Options{
+		MaxLimit:         10,
+		MinLimit:         2,
+		Adaptive:         true,
+		LatencyThreshold: 100 * time.Millisecond,
+		BackoffRatio:     0.5,
+		LowPriorityShare: 1,
+	})
+
+	slow := func() {
+		release, ok := l.Acquire(PriorityNormal)
+		if !ok {
+			t.Fatal("request shed")
+		}
+		release(time.Second, false)
+	}
+
+	// Multiplicative decrease down to MinLimit
+	for _, want := range []int{5, 2, 2} {
+		slow()
+		if got := l.Stats().Limit; got != want {
+			t.Fatalf("limit after slow response = %d, want %d", got, want)
+		}
+	}
+
+	// Failures back off like slow responses
+	release, _ := l.Acquire(PriorityNormal)
+	release(time.Millisecond, true)
+	if got := l.Stats().Limit; got != 2 {
+		t.Fatalf("limit after failure = %d, want 2", got)
+	}
+
+	// Additive increase while the limit is in use, capped at MaxLimit
+	for i := 0; i < 10; i++ {
+		var releases []func(time.Duration, bool)
+		for {
+			release, ok := l.Acquire(PriorityNormal)
+			if !ok {
+				break
+			}
+			releases = append(releases, release)
+		}
+		for _, release := range releases {
+			release(time.Millisecond, false)
+		}
+	}
+	if got := l.Stats().Limit; got != 10 {
+		t.Fatalf("limit after fast responses = %d, want 10", got)
+	}
+}
+
+func TestLimiterDoesNotGrowWhenIdle(t *testing.T) {
+	l := NewLimiter("test", Options{
+		MaxLimit:         10,
+		MinLimit:         1,
+		Adaptive:         true,
+		LatencyThreshold: time.Second,
+		BackoffRatio:     0.5,
+		LowPriorityShare: 1,
+	})
+	release, _ := l.Acquire(PriorityNormal)
+	release(2*time.Second, false)
+
+	// A single request at a time uses less than half of the limit of 5
+	for i := 0; i < 10; i++ {
+		release, _ := l.Acquire(PriorityNormal)
+		release(time.Millisecond, false)
+	}
+	if got := l.Stats().Limit; got != 5 {
+		t.Fatalf("limit = %d, want 5", got)
+	}
+}
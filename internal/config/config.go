@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +30,12 @@ type Config struct {
 	// Services
 	Services map[string]*ServiceConfig
 
+	// Routes
+	Routes []RouteConfig
+
+	// Proxy
+	Proxy *ProxyConfig
+
 	// JWT
 	JWT *JWTConfig
 
@@ -35,6 +45,9 @@ type Config struct {
 	// Rate Limiting
 	RateLimit *RateLimitConfig
 
+	// Concurrency limiting and load shedding
+	Concurrency *ConcurrencyConfig
+
 	// CORS
 	CORS *CORSConfig
 
@@ -44,6 +57,12 @@ type Config struct {
 	// Metrics
 	Metrics *MetricsConfig
 
+	// Logging
+	Logging *LoggingConfig
+
+	// Liveness and readiness probes
+	Health *HealthConfig
+
 	// Feature Flags
 	Features map[string]bool
 }
@@ -60,9 +79,22 @@ type RabbitMQConfig struct {
 	ChannelMax        int
 	FrameMax          int
 
+	// Reconnect backoff after the broker connection is lost
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
+
+	// PublisherConfirms waits up to ConfirmTimeout for the broker to confirm
+	// each publish; Mandatory makes unroutable messages fail instead of
+	// being dropped silently. Returns are only reported through confirms,
+	// so Mandatory requires PublisherConfirms.
+	PublisherConfirms bool
+	ConfirmTimeout    time.Duration
+	Mandatory         bool
+
 	Queues      []QueueConfig
 	Exchanges   []ExchangeConfig
 	Bindings    []BindingConfig
+	Events      []EventConfig
 	Policies    []PolicyConfig
 	Users       []UserConfig
 	Permissions []PermissionConfig
@@ -85,11 +117,24 @@ type ExchangeConfig struct {
 	Arguments  map[string]interface{} `json:"arguments"`
 }
 
+// BindingConfig binds a queue, or an exchange when DestinationType is
+// "exchange", to the Source exchange. Arguments are matched by headers
+// exchanges.
 type BindingConfig struct {
-	Source          string `json:"source"`
-	Destination     string `json:"destination"`
-	DestinationType string `json:"destination_type"`
-	RoutingKey      string `json:"routing_key"`
+	Source          string                 `json:"source"`
+	Destination     string                 `json:"destination"`
+	DestinationType string                 `json:"destination_type"`
+	RoutingKey      string                 `json:"routing_key"`
+	Arguments       map[string]interface{} `json:"arguments"`
+}
+
+// EventConfig publishes messages whose action is Name to Exchange, so the
+// bindings decide which queues receive them. RoutingKey defaults to Name.
+type EventConfig struct {
+	Name       string                 `json:"name"`
+	Exchange   string                 `json:"exchange"`
+	RoutingKey string                 `json:"routing_key"`
+	Headers    map[string]interface{} `json:"headers"`
 }
 
 type PolicyConfig struct {
@@ -121,6 +166,71 @@ type ServiceConfig struct {
 	CircuitBreaker bool
 	MaxConnections int
 	Weight         int
+	LoadBalancer   string
+	Upstreams      []UpstreamConfig
+	HealthCheck    HealthCheckConfig
+	Outlier        OutlierConfig
+	Breaker        BreakerConfig
+	Retry          RetryConfig
+	Transport      TransportConfig
+}
+
+// TransportConfig tunes the connection pool shared by requests to a service.
+// MaxConnections caps the connections per upstream host.
+type TransportConfig struct {
+	MaxIdleConns        int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+}
+
+// RetryConfig tunes how RetryCount retries are spaced and bounded
+type RetryConfig struct {
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	BodyLimit   int64
+	BudgetRatio float64
+	BudgetMin   int
+}
+
+// BreakerConfig tunes the per-service circuit breaker enabled by CircuitBreaker
+type BreakerConfig struct {
+	FailureRatio   float64
+	MinRequests    int
+	Window         time.Duration
+	OpenDuration   time.Duration
+	HalfOpenProbes int
+}
+
+// HealthCheckConfig configures active probing of upstream instances
+type HealthCheckConfig struct {
+	Enabled            bool
+	Path               string
+	Interval           time.Duration
+	Timeout            time.Duration
+	HealthyThreshold   int
+	UnhealthyThreshold int
+}
+
+// OutlierConfig configures passive ejection of failing upstream instances
+type OutlierConfig struct {
+	Enabled             bool
+	ConsecutiveFailures int
+	EjectionTime        time.Duration
+	MaxEjectionTime     time.Duration
+}
+
+type UpstreamConfig struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+type ProxyConfig struct {
+	// ReservedHeaders are stripped from every inbound request and may only be set by the gateway
+	ReservedHeaders []string
+	// TrustedProxies are IPs or CIDRs of load balancers whose forwarding headers are honoured
+	TrustedProxies []string
 }
 
 type JWTConfig struct {
@@ -136,6 +246,11 @@ type ServerConfig struct {
 	WriteTimeout   time.Duration
 	IdleTimeout    time.Duration
 	MaxHeaderBytes int
+	// ShutdownTimeout is the grace period for in-flight requests on SIGTERM
+	ShutdownTimeout time.Duration
+	// DrainDelay keeps serving after /readyz starts failing so load
+	// balancers notice before the listener closes
+	DrainDelay time.Duration
 }
 
 type RateLimitConfig struct {
@@ -143,6 +258,52 @@ type RateLimitConfig struct {
 	Requests int
 	Window   time.Duration
 	Strategy string
+	DryRun   bool
+	Policies []RateLimitPolicy
+}
+
+// RateLimitPolicy overrides the global limit for matching requests. Zero
+// Requests, Window or Strategy inherit the global values. Roles and Claims
+// only match on routes with Auth set, where the JWT has been verified.
+type RateLimitPolicy struct {
+	Name     string            `json:"name"`
+	Routes   []string          `json:"routes"`
+	Methods  []string          `json:"methods"`
+	Roles    []string          `json:"roles"`
+	Claims   map[string]string `json:"claims"`
+	Requests int               `json:"requests"`
+	Window   Duration          `json:"window"`
+	Strategy string            `json:"strategy"`
+	Priority int               `json:"priority"`
+	DryRun   bool              `json:"dry_run"`
+}
+
+// Duration is a time.Duration read from JSON strings such as "1m"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ConcurrencyConfig caps in-flight proxied requests globally and per service.
+// Per-service limits adapt between MinLimit and the service's MaxConnections.
+type ConcurrencyConfig struct {
+	Enabled          bool
+	GlobalLimit      int
+	Adaptive         bool
+	MinLimit         int
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+	LowPriorityShare float64
 }
 
 type CORSConfig struct {
@@ -166,13 +327,35 @@ type RedisConfig struct {
 	MinIdleConns int
 }
 
+// LoggingConfig controls structured logging. The level comes from LOG_LEVEL.
+type LoggingConfig struct {
+	Format    string // json or text
+	AccessLog bool
+	// SampleRates maps route templates to the fraction of successful
+	// requests written to the access log; errors are always logged
+	SampleRates       map[string]float64
+	RedactHeaders     []string
+	RedactQueryParams []string
+}
+
+// HealthConfig controls the /readyz dependency checks
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	CacheTTL     time.Duration
+	// RequireUpstreams makes an unreachable upstream service fail readiness
+	// instead of only degrading it
+	RequireUpstreams bool
+}
+
 type MetricsConfig struct {
-	Enabled         bool
-	Path            string
-	Port            string
-	TracingEnabled  bool
-	TracingProvider string
-	TracingEndpoint string
+	Enabled            bool
+	Path               string
+	Port               string
+	TracingEnabled     bool
+	TracingProvider    string // otlp (or jaeger), stdout, file
+	TracingEndpoint    string
+	TracingFile        string
+	TracingSampleRatio float64
 }
 
 // Load loads configuration from environment variables
@@ -189,15 +372,20 @@ func Load() *Config {
 		LogLevel:   getEnv("LOG_LEVEL", "info"),
 		Port:       getEnv("PORT", "8080"),
 
-		RabbitMQ:  loadRabbitMQConfig(),
-		Services:  loadServicesConfig(),
-		JWT:       loadJWTConfig(),
-		Server:    loadServerConfig(),
-		RateLimit: loadRateLimitConfig(),
-		CORS:      loadCORSConfig(),
-		Redis:     loadRedisConfig(),
-		Metrics:   loadMetricsConfig(),
-		Features:  loadFeatureFlags(),
+		RabbitMQ:    loadRabbitMQConfig(),
+		Services:    loadServicesConfig(),
+		Routes:      loadRoutesConfig(),
+		Proxy:       loadProxyConfig(),
+		JWT:         loadJWTConfig(),
+		Server:      loadServerConfig(),
+		RateLimit:   loadRateLimitConfig(),
+		Concurrency: loadConcurrencyConfig(),
+		CORS:        loadCORSConfig(),
+		Redis:       loadRedisConfig(),
+		Metrics:     loadMetricsConfig(),
+		Logging:     loadLoggingConfig(),
+		Health:      loadHealthConfig(),
+		Features:    loadFeatureFlags(),
 	}
 
 	// Validation
@@ -205,7 +393,6 @@ func Load() *Config {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	cfg.logConfig()
 	return cfg
 }
 
@@ -221,6 +408,13 @@ func loadRabbitMQConfig() *RabbitMQConfig {
 		ConnectionTimeout: getDurationEnv("RABBITMQ_CONNECTION_TIMEOUT", 30*time.Second),
 		ChannelMax:        getIntEnv("RABBITMQ_CHANNEL_MAX", 100),
 		FrameMax:          getIntEnv("RABBITMQ_FRAME_MAX", 131072),
+
+		ReconnectMinBackoff: getDurationEnv("RABBITMQ_RECONNECT_MIN_BACKOFF", 500*time.Millisecond),
+		ReconnectMaxBackoff: getDurationEnv("RABBITMQ_RECONNECT_MAX_BACKOFF", 30*time.Second),
+
+		PublisherConfirms: getBoolEnv("RABBITMQ_PUBLISHER_CONFIRMS", true),
+		ConfirmTimeout:    getDurationEnv("RABBITMQ_CONFIRM_TIMEOUT", 5*time.Second),
+		Mandatory:         getBoolEnv("RABBITMQ_MANDATORY", true),
 	}
 
 	// Build URL if not explicitly set
@@ -231,30 +425,46 @@ func loadRabbitMQConfig() *RabbitMQConfig {
 	}
 	cfg.URL = url
 
-	// Load queues from JSON
+	// Topology declared at startup and after every reconnect
+	cfg.Queues = defaultQueues()
 	if queuesJSON := getEnv("RABBITMQ_QUEUES", ""); queuesJSON != "" {
 		var queues []QueueConfig
-		if err := json.Unmarshal([]byte(queuesJSON), &queues); err == nil {
-			cfg.Queues = queues
-		} else {
-			log.Printf("Error parsing RABBITMQ_QUEUES: %v", err)
+		if err := json.Unmarshal([]byte(queuesJSON), &queues); err != nil {
+			log.Fatalf("Error parsing RABBITMQ_QUEUES: %v", err)
 		}
+		cfg.Queues = queues
 	}
 
 	// Load exchanges
 	if exchangesJSON := getEnv("RABBITMQ_EXCHANGES", ""); exchangesJSON != "" {
 		var exchanges []ExchangeConfig
-		if err := json.Unmarshal([]byte(exchangesJSON), &exchanges); err == nil {
-			cfg.Exchanges = exchanges
+		if err := json.Unmarshal([]byte(exchangesJSON), &exchanges); err != nil {
+			log.Fatalf("Error parsing RABBITMQ_EXCHANGES: %v", err)
 		}
+		cfg.Exchanges = exchanges
 	}
 
 	// Load bindings
 	if bindingsJSON := getEnv("RABBITMQ_BINDINGS", ""); bindingsJSON != "" {
 		var bindings []BindingConfig
-		if err := json.Unmarshal([]byte(bindingsJSON), &bindings); err == nil {
-			cfg.Bindings = bindings
+		if err := json.Unmarshal([]byte(bindingsJSON), &bindings); err != nil {
+			log.Fatalf("Error parsing RABBITMQ_BINDINGS: %v", err)
+		}
+		cfg.Bindings = bindings
+	}
+
+	// Load events published to exchanges
+	if eventsJSON := getEnv("RABBITMQ_EVENTS", ""); eventsJSON != "" {
+		var events []EventConfig
+		if err := json.Unmarshal([]byte(eventsJSON), &events); err != nil {
+			log.Fatalf("Error parsing RABBITMQ_EVENTS: %v", err)
+		}
+		for i := range events {
+			if events[i].RoutingKey == "" {
+				events[i].RoutingKey = events[i].Name
+			}
 		}
+		cfg.Events = events
 	}
 
 	// Load policies
@@ -278,6 +488,16 @@ func loadRabbitMQConfig() *RabbitMQConfig {
 	return cfg
 }
 
+// defaultQueues are the durable queues declared when RABBITMQ_QUEUES is not set
+func defaultQueues() []QueueConfig {
+	names := []string{"user_actions", "notifications", "data_requests", "default_queue"}
+	queues := make([]QueueConfig, len(names))
+	for i, name := range names {
+		queues[i] = QueueConfig{Name: name, Durable: true}
+	}
+	return queues
+}
+
 func loadServicesConfig() map[string]*ServiceConfig {
 	services := make(map[string]*ServiceConfig)
 
@@ -296,6 +516,58 @@ func loadServicesConfig() map[string]*ServiceConfig {
 			CircuitBreaker: getBoolEnv(prefix+"_SERVICE_CIRCUIT_BREAKER", true),
 			MaxConnections: getIntEnv(prefix+"_SERVICE_MAX_CONNECTIONS", 100),
 			Weight:         getIntEnv(prefix+"_SERVICE_WEIGHT", 1),
+			LoadBalancer:   getEnv(prefix+"_SERVICE_LOAD_BALANCER", "round-robin"),
+			HealthCheck: HealthCheckConfig{
+				Enabled:            getBoolEnv(prefix+"_SERVICE_HEALTH_CHECK", true),
+				Path:               getEnv(prefix+"_SERVICE_HEALTH_PATH", "/health"),
+				Interval:           getDurationEnv(prefix+"_SERVICE_HEALTH_INTERVAL", 10*time.Second),
+				Timeout:            getDurationEnv(prefix+"_SERVICE_HEALTH_TIMEOUT", 2*time.Second),
+				HealthyThreshold:   getIntEnv(prefix+"_SERVICE_HEALTHY_THRESHOLD", 2),
+				UnhealthyThreshold: getIntEnv(prefix+"_SERVICE_UNHEALTHY_THRESHOLD", 3),
+			},
+			Outlier: OutlierConfig{
+				Enabled:             getBoolEnv(prefix+"_SERVICE_OUTLIER_DETECTION", true),
+				ConsecutiveFailures: getIntEnv(prefix+"_SERVICE_OUTLIER_FAILURES", 5),
+				EjectionTime:        getDurationEnv(prefix+"_SERVICE_EJECTION_TIME", 30*time.Second),
+				MaxEjectionTime:     getDurationEnv(prefix+"_SERVICE_MAX_EJECTION_TIME", 5*time.Minute),
+			},
+			Breaker: BreakerConfig{
+				FailureRatio:   getFloatEnv(prefix+"_SERVICE_CB_FAILURE_RATIO", 0.5),
+				MinRequests:    getIntEnv(prefix+"_SERVICE_CB_MIN_REQUESTS", 20),
+				Window:         getDurationEnv(prefix+"_SERVICE_CB_WINDOW", 30*time.Second),
+				OpenDuration:   getDurationEnv(prefix+"_SERVICE_CB_OPEN_DURATION", 30*time.Second),
+				HalfOpenProbes: getIntEnv(prefix+"_SERVICE_CB_HALF_OPEN_PROBES", 3),
+			},
+			Retry: RetryConfig{
+				Backoff:     getDurationEnv(prefix+"_SERVICE_RETRY_BACKOFF", 100*time.Millisecond),
+				MaxBackoff:  getDurationEnv(prefix+"_SERVICE_RETRY_MAX_BACKOFF", 2*time.Second),
+				BodyLimit:   int64(getIntEnv(prefix+"_SERVICE_RETRY_BODY_LIMIT", 1<<20)),
+				BudgetRatio: getFloatEnv(prefix+"_SERVICE_RETRY_BUDGET_RATIO", 0.2),
+				BudgetMin:   getIntEnv(prefix+"_SERVICE_RETRY_BUDGET_MIN", 10),
+			},
+			Transport: TransportConfig{
+				MaxIdleConns:        getIntEnv(prefix+"_SERVICE_MAX_IDLE_CONNS", 50),
+				IdleConnTimeout:     getDurationEnv(prefix+"_SERVICE_IDLE_CONN_TIMEOUT", 90*time.Second),
+				KeepAlive:           getDurationEnv(prefix+"_SERVICE_KEEP_ALIVE", 30*time.Second),
+				DialTimeout:         getDurationEnv(prefix+"_SERVICE_DIAL_TIMEOUT", 5*time.Second),
+				TLSHandshakeTimeout: getDurationEnv(prefix+"_SERVICE_TLS_HANDSHAKE_TIMEOUT", 5*time.Second),
+			},
+		}
+
+		// Load upstream instances from JSON, falling back to the single URL
+		if upstreamsJSON := getEnv(prefix+"_SERVICE_UPSTREAMS", ""); upstreamsJSON != "" {
+			if err := json.Unmarshal([]byte(upstreamsJSON), &cfg.Upstreams); err != nil {
+				log.Fatalf("Error parsing %s_SERVICE_UPSTREAMS: %v", prefix, err)
+			}
+		}
+		if len(cfg.Upstreams) == 0 {
+			cfg.Upstreams = []UpstreamConfig{{URL: cfg.URL, Weight: cfg.Weight}}
+		}
+		// An omitted weight defaults to 1; negative weights fail validation
+		for i := range cfg.Upstreams {
+			if cfg.Upstreams[i].Weight == 0 {
+				cfg.Upstreams[i].Weight = 1
+			}
 		}
 
 		services[strings.ToLower(name)] = cfg
@@ -304,6 +576,30 @@ func loadServicesConfig() map[string]*ServiceConfig {
 	return services
 }
 
+func loadProxyConfig() *ProxyConfig {
+	// Identity headers set from the JWT are always reserved
+	identityHeaders := []string{"X-User-ID", "X-Username"}
+
+	reserved := getSliceEnv("PROXY_RESERVED_HEADERS", nil)
+	for _, h := range identityHeaders {
+		found := false
+		for _, r := range reserved {
+			if strings.EqualFold(r, h) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reserved = append(reserved, h)
+		}
+	}
+
+	return &ProxyConfig{
+		ReservedHeaders: reserved,
+		TrustedProxies:  getSliceEnv("TRUSTED_PROXIES", nil),
+	}
+}
+
 func loadJWTConfig() *JWTConfig {
 	return &JWTConfig{
 		Secret:            mustGetEnv("JWT_SECRET"), // Required field
@@ -316,30 +612,65 @@ func loadJWTConfig() *JWTConfig {
 
 func loadServerConfig() *ServerConfig {
 	return &ServerConfig{
-		ReadTimeout:    getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
-		WriteTimeout:   getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
-		IdleTimeout:    getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
-		MaxHeaderBytes: getIntEnv("SERVER_MAX_HEADER_BYTES", 1048576),
+		ReadTimeout:     getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:    getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:     getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+		MaxHeaderBytes:  getIntEnv("SERVER_MAX_HEADER_BYTES", 1048576),
+		ShutdownTimeout: getDurationEnv("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		DrainDelay:      getDurationEnv("SERVER_DRAIN_DELAY", 5*time.Second),
 	}
 }
 
 func loadRateLimitConfig() *RateLimitConfig {
-	return &RateLimitConfig{
+	cfg := &RateLimitConfig{
 		Enabled:  getBoolEnv("RATE_LIMIT_ENABLED", true),
 		Requests: getIntEnv("RATE_LIMIT_REQUESTS", 1000),
 		Window:   getDurationEnv("RATE_LIMIT_WINDOW", time.Minute),
 		Strategy: getEnv("RATE_LIMIT_STRATEGY", "token-bucket"),
+		DryRun:   getBoolEnv("RATE_LIMIT_DRY_RUN", false),
+		Policies: defaultRateLimitPolicies(),
+	}
+
+	// Load policies from JSON
+	if policiesJSON := getEnv("RATE_LIMIT_POLICIES", ""); policiesJSON != "" {
+		var policies []RateLimitPolicy
+		if err := json.Unmarshal([]byte(policiesJSON), &policies); err != nil {
+			log.Fatalf("Error parsing RATE_LIMIT_POLICIES: %v", err)
+		}
+		cfg.Policies = policies
+	}
+
+	return cfg
+}
+
+func defaultRateLimitPolicies() []RateLimitPolicy {
+	return []RateLimitPolicy{
+		// Slow down login brute-forcing
+		{Name: "auth", Routes: []string{"/api/v1/auth/*path"}, Requests: 20, Window: Duration(time.Minute)},
+		{Name: "create-post", Routes: []string{"/api/v1/posts"}, Methods: []string{"POST"}, Requests: 30, Window: Duration(time.Minute)},
+	}
+}
+
+func loadConcurrencyConfig() *ConcurrencyConfig {
+	return &ConcurrencyConfig{
+		Enabled:          getBoolEnv("CONCURRENCY_LIMIT_ENABLED", true),
+		GlobalLimit:      getIntEnv("CONCURRENCY_GLOBAL_LIMIT", 2000),
+		Adaptive:         getBoolEnv("CONCURRENCY_ADAPTIVE", true),
+		MinLimit:         getIntEnv("CONCURRENCY_MIN_LIMIT", 10),
+		LatencyThreshold: getDurationEnv("CONCURRENCY_LATENCY_THRESHOLD", 2*time.Second),
+		BackoffRatio:     getFloatEnv("CONCURRENCY_BACKOFF_RATIO", 0.9),
+		LowPriorityShare: getFloatEnv("CONCURRENCY_LOW_PRIORITY_SHARE", 0.8),
 	}
 }
 
 func loadCORSConfig() *CORSConfig {
 	return &CORSConfig{
 		AllowOrigins:     getSliceEnv("CORS_ALLOW_ORIGINS", []string{"*"}),
-		AllowMethods:     getSliceEnv("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		AllowMethods:     getSliceEnv("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 		AllowHeaders:     getSliceEnv("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization"}),
-		ExposeHeaders:    getSliceEnv("CORS_EXPOSE_HEADERS", []string{"Content-Length"}),
+		ExposeHeaders:    getSliceEnv("CORS_EXPOSE_HEADERS", []string{"Content-Length", "X-Request-ID"}),
 		MaxAge:           getDurationEnv("CORS_MAX_AGE", 12*time.Hour),
-		AllowCredentials: getBoolEnv("CORS_ALLOW_CREDENTIALS", true),
+		AllowCredentials: getBoolEnv("CORS_ALLOW_CREDENTIALS", false),
 	}
 }
 
@@ -371,14 +702,51 @@ func loadRedisConfig() *RedisConfig {
 	return cfg
 }
 
+func loadLoggingConfig() *LoggingConfig {
+	cfg := &LoggingConfig{
+		Format:      getEnv("LOG_FORMAT", "json"),
+		AccessLog:   getBoolEnv("ACCESS_LOG_ENABLED", true),
+		SampleRates: make(map[string]float64),
+		RedactHeaders: getSliceEnv("LOG_REDACT_HEADERS",
+			[]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}),
+		RedactQueryParams: getSliceEnv("LOG_REDACT_QUERY_PARAMS",
+			[]string{"token", "access_token", "refresh_token", "id_token", "api_key", "jwt"}),
+	}
+
+	// Route=rate pairs, e.g. /api/v1/posts=0.1,/api/v1/posts/*path=0.1
+	for _, pair := range getSliceEnv("ACCESS_LOG_SAMPLE_RATES", nil) {
+		route, rate, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Fatalf("Error parsing ACCESS_LOG_SAMPLE_RATES: expected route=rate, got %q", pair)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil {
+			log.Fatalf("Error parsing ACCESS_LOG_SAMPLE_RATES: %v", err)
+		}
+		cfg.SampleRates[strings.TrimSpace(route)] = value
+	}
+
+	return cfg
+}
+
+func loadHealthConfig() *HealthConfig {
+	return &HealthConfig{
+		CheckTimeout:     getDurationEnv("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		CacheTTL:         getDurationEnv("HEALTH_CACHE_TTL", 5*time.Second),
+		RequireUpstreams: getBoolEnv("READINESS_REQUIRE_UPSTREAMS", false),
+	}
+}
+
 func loadMetricsConfig() *MetricsConfig {
 	return &MetricsConfig{
-		Enabled:         getBoolEnv("METRICS_ENABLED", true),
-		Path:            getEnv("METRICS_PATH", "/metrics"),
-		Port:            getEnv("METRICS_PORT", "9090"),
-		TracingEnabled:  getBoolEnv("TRACING_ENABLED", false),
-		TracingProvider: getEnv("TRACING_PROVIDER", "jaeger"),
-		TracingEndpoint: getEnv("TRACING_ENDPOINT", "http://jaeger:14268"),
+		Enabled:            getBoolEnv("METRICS_ENABLED", true),
+		Path:               getEnv("METRICS_PATH", "/metrics"),
+		Port:               getEnv("METRICS_PORT", "9090"),
+		TracingEnabled:     getBoolEnv("TRACING_ENABLED", false),
+		TracingProvider:    getEnv("TRACING_PROVIDER", "otlp"),
+		TracingEndpoint:    getEnv("TRACING_ENDPOINT", "http://jaeger:4318"),
+		TracingFile:        getEnv("TRACING_FILE", "traces.json"),
+		TracingSampleRatio: getFloatEnv("TRACING_SAMPLE_RATIO", 1.0),
 	}
 }
 
@@ -404,29 +772,238 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.RabbitMQ.Mandatory && !c.RabbitMQ.PublisherConfirms {
+		return fmt.Errorf("RABBITMQ_MANDATORY requires RABBITMQ_PUBLISHER_CONFIRMS")
+	}
+
+	if err := c.RabbitMQ.validateTopology(); err != nil {
+		return err
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Strategy {
+		case "token-bucket", "fixed-window", "sliding-window", "gcra":
+		default:
+			return fmt.Errorf("unknown RATE_LIMIT_STRATEGY %q", c.RateLimit.Strategy)
+		}
+		if c.RateLimit.Requests <= 0 || c.RateLimit.Window <= 0 {
+			return fmt.Errorf("RATE_LIMIT_REQUESTS and RATE_LIMIT_WINDOW must be positive")
+		}
+		if err := c.RateLimit.validatePolicies(); err != nil {
+			return err
+		}
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("unknown LOG_LEVEL %q", c.LogLevel)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		return fmt.Errorf("LOG_FORMAT must be json or text")
+	}
+	for route, rate := range c.Logging.SampleRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("access log sample rate for %s must be in [0, 1]", route)
+		}
+	}
+
+	if c.Metrics.TracingEnabled {
+		switch c.Metrics.TracingProvider {
+		case "otlp", "jaeger", "stdout", "file":
+		default:
+			return fmt.Errorf("unknown TRACING_PROVIDER %q", c.Metrics.TracingProvider)
+		}
+		if c.Metrics.TracingSampleRatio < 0 || c.Metrics.TracingSampleRatio > 1 {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO must be in [0, 1]")
+		}
+	}
+
+	if c.Concurrency.Enabled {
+		if c.Concurrency.BackoffRatio <= 0 || c.Concurrency.BackoffRatio >= 1 {
+			return fmt.Errorf("CONCURRENCY_BACKOFF_RATIO must be in (0, 1)")
+		}
+		if c.Concurrency.LowPriorityShare <= 0 || c.Concurrency.LowPriorityShare > 1 {
+			return fmt.Errorf("CONCURRENCY_LOW_PRIORITY_SHARE must be in (0, 1]")
+		}
+	}
+
+	for _, p := range c.Proxy.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return fmt.Errorf("invalid trusted proxy %q", p)
+		}
+	}
+
+	for key, svc := range c.Services {
+		switch svc.LoadBalancer {
+		case "round-robin", "least-connections", "consistent-hash":
+		default:
+			return fmt.Errorf("service %s: unknown load balancer %q", key, svc.LoadBalancer)
+		}
+		if svc.CircuitBreaker {
+			if svc.Breaker.FailureRatio <= 0 || svc.Breaker.FailureRatio > 1 {
+				return fmt.Errorf("service %s: circuit breaker failure ratio must be in (0, 1]", key)
+			}
+			if svc.Breaker.HalfOpenProbes < 1 {
+				return fmt.Errorf("service %s: circuit breaker needs at least one half-open probe", key)
+			}
+		}
+		if svc.HealthCheck.Enabled && svc.HealthCheck.Interval <= 0 {
+			return fmt.Errorf("service %s: health check interval must be positive", key)
+		}
+		for _, u := range svc.Upstreams {
+			if parsed, err := url.Parse(u.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return fmt.Errorf("service %s: invalid upstream URL %q", key, u.URL)
+			}
+			if u.Weight < 0 {
+				return fmt.Errorf("service %s: upstream %s has negative weight %d", key, u.URL, u.Weight)
+			}
+		}
+	}
+
+	if err := c.validateRoutes(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// Log configuration (without secrets)
-func (c *Config) logConfig() {
-	log.Println("=== Configuration ===")
-	log.Printf("App: %s v%s (%s)", c.AppName, c.AppVersion, c.AppEnv)
-	log.Printf("Port: %s", c.Port)
-	log.Printf("Log Level: %s", c.LogLevel)
+// validateTopology rejects RabbitMQ declarations that conflict with each
+// other or reference exchanges that are not declared, including the
+// exchanges events are published to
+func (c *RabbitMQConfig) validateTopology() error {
+	exchanges := make(map[string]ExchangeConfig, len(c.Exchanges))
+	for _, ex := range c.Exchanges {
+		if ex.Name == "" || strings.HasPrefix(ex.Name, "amq.") {
+			return fmt.Errorf("RABBITMQ_EXCHANGES: invalid exchange name %q", ex.Name)
+		}
+		switch ex.Type {
+		case "direct", "fanout", "topic", "headers":
+		default:
+			if !strings.HasPrefix(ex.Type, "x-") {
+				return fmt.Errorf("RABBITMQ_EXCHANGES: exchange %s has unknown type %q", ex.Name, ex.Type)
+			}
+		}
+		if prev, ok := exchanges[ex.Name]; ok && !reflect.DeepEqual(prev, ex) {
+			return fmt.Errorf("RABBITMQ_EXCHANGES: conflicting declarations of exchange %s", ex.Name)
+		}
+		exchanges[ex.Name] = ex
+	}
 
-	log.Printf("RabbitMQ: %s@%s:%s",
-		c.RabbitMQ.User, c.RabbitMQ.Host, c.RabbitMQ.Port)
-	log.Printf("Queues: %d", len(c.RabbitMQ.Queues))
+	queues := make(map[string]QueueConfig, len(c.Queues))
+	for _, q := range c.Queues {
+		if q.Name == "" {
+			return fmt.Errorf("RABBITMQ_QUEUES: queue name is required")
+		}
+		if prev, ok := queues[q.Name]; ok && !reflect.DeepEqual(prev, q) {
+			return fmt.Errorf("RABBITMQ_QUEUES: conflicting declarations of queue %s", q.Name)
+		}
+		queues[q.Name] = q
+	}
 
-	log.Printf("Services: %d", len(c.Services))
-	for name, svc := range c.Services {
-		log.Printf("  %s: %s (timeout: %v)", name, svc.URL, svc.Timeout)
+	declared := func(name string) bool {
+		_, ok := exchanges[name]
+		return ok || strings.HasPrefix(name, "amq.")
+	}
+	for _, b := range c.Bindings {
+		if !declared(b.Source) {
+			return fmt.Errorf("RABBITMQ_BINDINGS: source exchange %q is not declared", b.Source)
+		}
+		switch b.DestinationType {
+		case "", "queue":
+			if _, ok := queues[b.Destination]; !ok {
+				return fmt.Errorf("RABBITMQ_BINDINGS: destination queue %q is not declared", b.Destination)
+			}
+		case "exchange":
+			if !declared(b.Destination) {
+				return fmt.Errorf("RABBITMQ_BINDINGS: destination exchange %q is not declared", b.Destination)
+			}
+		default:
+			return fmt.Errorf("RABBITMQ_BINDINGS: unknown destination_type %q", b.DestinationType)
+		}
 	}
 
-	log.Printf("Redis Enabled: %v", c.Redis.Enabled)
-	log.Printf("Metrics Enabled: %v", c.Metrics.Enabled)
-	log.Printf("Rate Limit: %d/%v", c.RateLimit.Requests, c.RateLimit.Window)
-	log.Println("======================")
+	events := make(map[string]bool, len(c.Events))
+	for _, ev := range c.Events {
+		if ev.Name == "" {
+			return fmt.Errorf("RABBITMQ_EVENTS: event name is required")
+		}
+		if events[ev.Name] {
+			return fmt.Errorf("RABBITMQ_EVENTS: duplicate event %s", ev.Name)
+		}
+		events[ev.Name] = true
+		if !declared(ev.Exchange) {
+			return fmt.Errorf("RABBITMQ_EVENTS: exchange %q of event %s is not declared", ev.Exchange, ev.Name)
+		}
+	}
+	return nil
+}
+
+// validatePolicies checks rate limit policies for missing names and bad matchers
+func (c *RateLimitConfig) validatePolicies() error {
+	names := make(map[string]bool)
+	for i, p := range c.Policies {
+		if p.Name == "" {
+			return fmt.Errorf("rate limit policy %d: name is required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("rate limit policy %s: duplicate name", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Requests < 0 || p.Window < 0 {
+			return fmt.Errorf("rate limit policy %s: requests and window must not be negative", p.Name)
+		}
+		for _, r := range p.Routes {
+			if !strings.HasPrefix(r, "/") {
+				return fmt.Errorf("rate limit policy %s: route %q must start with '/'", p.Name, r)
+			}
+		}
+		for _, m := range p.Methods {
+			if !isKnownMethod(strings.ToUpper(m)) {
+				return fmt.Errorf("rate limit policy %s: unknown method %q", p.Name, m)
+			}
+		}
+	}
+	return nil
+}
+
+// LogSummary logs the loaded configuration without secrets. It is called
+// once logging is set up so the summary goes through the configured handler.
+func (c *Config) LogSummary() {
+	slog.Info("Configuration loaded",
+		"app", c.AppName,
+		"version", c.AppVersion,
+		"env", c.AppEnv,
+		"port", c.Port,
+		"log_level", c.LogLevel,
+		"rabbitmq", c.RabbitMQ.User+"@"+c.RabbitMQ.Host+":"+c.RabbitMQ.Port,
+		"exchanges", len(c.RabbitMQ.Exchanges),
+		"queues", len(c.RabbitMQ.Queues),
+		"bindings", len(c.RabbitMQ.Bindings),
+		"events", len(c.RabbitMQ.Events),
+		"services", len(c.Services),
+		"routes", len(c.Routes),
+		"redis_enabled", c.Redis.Enabled,
+		"metrics_enabled", c.Metrics.Enabled,
+		"tracing_enabled", c.Metrics.TracingEnabled,
+		"tracing_provider", c.Metrics.TracingProvider,
+		"rate_limit", fmt.Sprintf("%d/%v", c.RateLimit.Requests, c.RateLimit.Window),
+		"rate_limit_policies", len(c.RateLimit.Policies),
+		"rate_limit_dry_run", c.RateLimit.DryRun,
+		"concurrency_enabled", c.Concurrency.Enabled,
+		"concurrency_global_limit", c.Concurrency.GlobalLimit,
+		"concurrency_adaptive", c.Concurrency.Adaptive,
+	)
+	for name, svc := range c.Services {
+		slog.Info("Service configured",
+			"service", name,
+			"url", svc.URL,
+			"timeout", svc.Timeout.String(),
+			"upstreams", len(svc.Upstreams),
+			"load_balancer", svc.LoadBalancer,
+		)
+	}
 }
 
 // Helper functions
@@ -455,6 +1032,15 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value, exists := os.LookupEnv(key); exists {
 		if duration, err := time.ParseDuration(value); err == nil {
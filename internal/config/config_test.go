@@ -0,0 +1,63 @@
+package config
+
+import "testing"
+
+// validConfig returns a minimal configuration that passes Validate
+func validConfig() *Config {
+	return &Config{
+		AppEnv:   "development",
+		LogLevel: "info",
+		RabbitMQ: &RabbitMQConfig{PublisherConfirms: true, Mandatory: true},
+		Services: map[string]*ServiceConfig{
+			"post": {
+				Name:         "post",
+				URL:          "http://post-service:8082",
+				LoadBalancer: "round-robin",
+				Upstreams:    []UpstreamConfig{{URL: "http://post-service:8082", Weight: 1}},
+			},
+		},
+		Routes:      []RouteConfig{{Path: "/api/v1/posts/*path", Service: "post"}},
+		Proxy:       &ProxyConfig{},
+		JWT:         &JWTConfig{Secret: "test-secret"},
+		RateLimit:   &RateLimitConfig{},
+		Concurrency: &ConcurrencyConfig{},
+		Metrics:     &MetricsConfig{},
+		Logging:     &LoggingConfig{Format: "json"},
+	}
+}
+
+func TestValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateMandatoryRequiresConfirms(t *testing.T) {
+	tests := []struct {
+		confirms, mandatory bool
+		wantErr             bool
+	}{
+		{true, true, false},
+		{true, false, false},
+		{false, false, false},
+		{false, true, true},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.RabbitMQ.PublisherConfirms = tt.confirms
+		cfg.RabbitMQ.Mandatory = tt.mandatory
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("confirms=%v mandatory=%v: error = %v, want error %v", tt.confirms, tt.mandatory, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateUpstreamWeights(t *testing.T) {
+	for weight, wantErr := range map[int]bool{1: false, 5: false, 0: false, -1: true} {
+		cfg := validConfig()
+		cfg.Services["post"].Upstreams[0].Weight = weight
+		if err := cfg.Validate(); (err != nil) != wantErr {
+			t.Errorf("weight %d: error = %v, want error %v", weight, err, wantErr)
+		}
+	}
+}
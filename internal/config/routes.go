@@ -0,0 +1,251 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// anyMethods mirrors the methods gin registers for RouterGroup.Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete,
+	http.MethodConnect, http.MethodTrace,
+}
+
+type RouteConfig struct {
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	Service     string   `json:"service"`
+	Auth        bool     `json:"auth"`
+	StripPrefix string   `json:"strip_prefix"`
+	Rewrite     string   `json:"rewrite"`
+}
+
+// MethodList returns the HTTP methods the route is registered for.
+// An empty list or "*" means every method.
+func (r RouteConfig) MethodList() []string {
+	if len(r.Methods) == 0 {
+		return anyMethods
+	}
+	methods := make([]string, 0, len(r.Methods))
+	for _, m := range r.Methods {
+		if m == "*" {
+			return anyMethods
+		}
+		methods = append(methods, strings.ToUpper(m))
+	}
+	return methods
+}
+
+func loadRoutesConfig() []RouteConfig {
+	// Routes file takes precedence over inline JSON
+	if path := getEnv("ROUTES_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Error reading ROUTES_FILE: %v", err)
+		}
+		var routes []RouteConfig
+		if err := json.Unmarshal(data, &routes); err != nil {
+			log.Fatalf("Error parsing ROUTES_FILE: %v", err)
+		}
+		return routes
+	}
+
+	if routesJSON := getEnv("ROUTES", ""); routesJSON != "" {
+		var routes []RouteConfig
+		if err := json.Unmarshal([]byte(routesJSON), &routes); err != nil {
+			log.Fatalf("Error parsing ROUTES: %v", err)
+		}
+		return routes
+	}
+
+	return defaultRoutes()
+}
+
+func defaultRoutes() []RouteConfig {
+	return []RouteConfig{
+		// Auth service routes (public - no JWT)
+		{Path: "/api/v1/auth/*path", Service: "auth"},
+		{Path: "/api/v1/users/*path", Service: "auth"},
+		{Path: "/api/v1/roles/*path", Service: "auth"},
+		{Path: "/api/v1/permissions/*path", Service: "auth"},
+
+		// Public read-only routes (no JWT required)
+		{Path: "/api/v1/posts", Methods: []string{"GET"}, Service: "post"},
+		{Path: "/api/v1/posts/*path", Methods: []string{"GET"}, Service: "post"},
+		{Path: "/api/v1/comments", Methods: []string{"GET"}, Service: "comment"},
+		{Path: "/api/v1/comments/*path", Methods: []string{"GET"}, Service: "comment"},
+
+		// Protected routes (require JWT for write operations)
+		{Path: "/api/v1/posts", Methods: []string{"POST"}, Service: "post", Auth: true},
+		{Path: "/api/v1/posts/:id", Methods: []string{"PATCH", "DELETE"}, Service: "post", Auth: true},
+		{Path: "/api/v1/posts/:id/like", Methods: []string{"POST", "DELETE"}, Service: "post", Auth: true},
+		{Path: "/api/v1/comments", Methods: []string{"POST"}, Service: "comment", Auth: true},
+		{Path: "/api/v1/comments/*path", Methods: []string{"PATCH", "DELETE"}, Service: "comment", Auth: true},
+	}
+}
+
+// validateRoutes checks the route table for unknown services, malformed
+// patterns and routes that overlap or shadow each other for the same method.
+func (c *Config) validateRoutes() error {
+	var errs []string
+
+	for i, r := range c.Routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Sprintf("route %d: path %q must start with '/'", i, r.Path))
+			continue
+		}
+		if r.Service == "" {
+			errs = append(errs, fmt.Sprintf("route %s: service is required", r.Path))
+		} else if _, ok := c.Services[r.Service]; !ok {
+			errs = append(errs, fmt.Sprintf("route %s: unknown service %q", r.Path, r.Service))
+		}
+		for _, m := range r.MethodList() {
+			if !isKnownMethod(m) {
+				errs = append(errs, fmt.Sprintf("route %s: unknown method %q", r.Path, m))
+			}
+		}
+		segs := strings.Split(strings.Trim(r.Path, "/"), "/")
+		for j, seg := range segs {
+			if strings.HasPrefix(seg, "*") && j != len(segs)-1 {
+				errs = append(errs, fmt.Sprintf("route %s: catch-all must be the last segment", r.Path))
+			}
+			if (strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*")) && len(seg) == 1 {
+				errs = append(errs, fmt.Sprintf("route %s: wildcard must be named", r.Path))
+			}
+		}
+		if r.StripPrefix != "" && r.Rewrite != "" {
+			errs = append(errs, fmt.Sprintf("route %s: strip_prefix and rewrite are mutually exclusive", r.Path))
+		}
+	}
+
+	for i := 0; i < len(c.Routes); i++ {
+		for j := i + 1; j < len(c.Routes); j++ {
+			a, b := c.Routes[i], c.Routes[j]
+			method := sharedMethod(a, b)
+			if method == "" {
+				continue
+			}
+			if reason := routeConflict(a.Path, b.Path); reason != "" {
+				errs = append(errs, fmt.Sprintf("%s %s and %s %s: %s", method, a.Path, method, b.Path, reason))
+			}
+		}
+	}
+
+	// Configured routes share the engine with the gateway's own endpoints
+	for _, b := range builtinRoutes {
+		for _, r := range c.Routes {
+			method := sharedMethod(b, r)
+			if method == "" {
+				continue
+			}
+			if reason := routeConflict(b.Path, r.Path); reason != "" {
+				errs = append(errs, fmt.Sprintf("%s %s (built-in) and %s %s: %s", method, b.Path, method, r.Path, reason))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid routes:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// builtinRoutes lists the endpoints cmd/main.go registers on the API
+// listener next to the route table
+var builtinRoutes = []RouteConfig{
+	{Path: "/livez", Methods: []string{http.MethodGet}},
+	{Path: "/readyz", Methods: []string{http.MethodGet}},
+	{Path: "/health", Methods: []string{http.MethodGet}},
+}
+
+func isKnownMethod(method string) bool {
+	for _, m := range anyMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func sharedMethod(a, b RouteConfig) string {
+	for _, ma := range a.MethodList() {
+		for _, mb := range b.MethodList() {
+			if ma == mb {
+				return ma
+			}
+		}
+	}
+	return ""
+}
+
+// routeConflict reports why two patterns registered for the same method
+// cannot coexist, or "" if they can.
+func routeConflict(a, b string) string {
+	sa := strings.Split(strings.Trim(a, "/"), "/")
+	sb := strings.Split(strings.Trim(b, "/"), "/")
+
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		pa, pb := sa[i], sb[i]
+		wa, wb := isWildcard(pa), isWildcard(pb)
+
+		switch {
+		case strings.HasPrefix(pa, "*") || strings.HasPrefix(pb, "*"):
+			if len(sa) == len(sb) && pa == pb {
+				return "duplicate route"
+			}
+			return "shadowed by catch-all"
+		case wa && wb:
+			if pa != pb {
+				return fmt.Sprintf("conflicting wildcards %s and %s", pa, pb)
+			}
+		case wa != wb:
+			// Static segments take priority over params, both are reachable
+			return ""
+		case pa != pb:
+			return ""
+		}
+	}
+
+	if len(sa) == len(sb) {
+		return "duplicate route"
+	}
+	return ""
+}
+
+func isWildcard(seg string) bool {
+	return strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*")
+}
+
+// MatchPath reports whether a request path matches a gin route pattern
+func MatchPath(pattern, path string) bool {
+	for {
+		switch {
+		case pattern == "":
+			return path == ""
+		case pattern[0] == '*':
+			return true
+		case pattern[0] == ':':
+			pi := strings.IndexByte(pattern, '/')
+			if pi < 0 {
+				pi = len(pattern)
+			}
+			vi := strings.IndexByte(path, '/')
+			if vi < 0 {
+				vi = len(path)
+			}
+			if vi == 0 {
+				return false
+			}
+			pattern, path = pattern[pi:], path[vi:]
+		case path == "" || pattern[0] != path[0]:
+			return false
+		default:
+			pattern, path = pattern[1:], path[1:]
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateRoutesBuiltinConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  []RouteConfig
+		wantErr string
+	}{
+		{"default routes", defaultRoutes(), ""},
+		{"root catch-all", []RouteConfig{{Path: "/*path", Service: "post"}}, "GET /livez (built-in) and GET /*path: shadowed by catch-all"},
+		{"root catch-all for other methods", []RouteConfig{{Path: "/*path", Methods: []string{"POST"}, Service: "post"}}, ""},
+		{"health route", []RouteConfig{{Path: "/health", Methods: []string{"GET"}, Service: "post"}}, "GET /health (built-in) and GET /health: duplicate route"},
+		{"admin paths are not on the API listener", []RouteConfig{{Path: "/admin/*path", Service: "post"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Routes: tt.routes,
+				Services: map[string]*ServiceConfig{
+					"auth": {}, "post": {}, "comment": {},
+				},
+			}
+			err := cfg.validateRoutes()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		routes  []RouteConfig
+		wantErr string
+	}{
+		{
+			"duplicate route",
+			[]RouteConfig{{Path: "/api/v1/posts", Service: "post"}, {Path: "/api/v1/posts", Service: "comment"}},
+			"GET /api/v1/posts and GET /api/v1/posts: duplicate route",
+		},
+		{
+			"duplicate routes with different methods",
+			[]RouteConfig{
+				{Path: "/api/v1/posts", Methods: []string{"GET"}, Service: "post"},
+				{Path: "/api/v1/posts", Methods: []string{"POST"}, Service: "post"},
+			},
+			"",
+		},
+		{
+			"catch-all shadows static route",
+			[]RouteConfig{{Path: "/api/v1/posts/*path", Service: "post"}, {Path: "/api/v1/posts/feed", Service: "post"}},
+			"GET /api/v1/posts/*path and GET /api/v1/posts/feed: shadowed by catch-all",
+		},
+		{
+			"catch-all shadows param route",
+			[]RouteConfig{{Path: "/api/v1/posts/:id", Service: "post"}, {Path: "/api/v1/posts/*path", Service: "post"}},
+			"GET /api/v1/posts/:id and GET /api/v1/posts/*path: shadowed by catch-all",
+		},
+		{
+			"conflicting param names",
+			[]RouteConfig{{Path: "/api/v1/posts/:id", Service: "post"}, {Path: "/api/v1/posts/:slug/comments", Service: "comment"}},
+			"conflicting wildcards :id and :slug",
+		},
+		{
+			"same param name on different depths",
+			[]RouteConfig{{Path: "/api/v1/posts/:id", Service: "post"}, {Path: "/api/v1/posts/:id/comments", Service: "comment"}},
+			"",
+		},
+		{
+			"static route next to param route",
+			[]RouteConfig{{Path: "/api/v1/posts/:id", Service: "post"}, {Path: "/api/v1/posts/feed", Service: "post"}},
+			"",
+		},
+		{
+			"unknown service",
+			[]RouteConfig{{Path: "/api/v1/users/*path", Service: "user"}},
+			`route /api/v1/users/*path: unknown service "user"`,
+		},
+		{
+			"unnamed param",
+			[]RouteConfig{{Path: "/api/v1/posts/:", Service: "post"}},
+			"route /api/v1/posts/:: wildcard must be named",
+		},
+		{
+			"unnamed catch-all",
+			[]RouteConfig{{Path: "/api/v1/posts/*", Service: "post"}},
+			"route /api/v1/posts/*: wildcard must be named",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Routes: tt.routes,
+				Services: map[string]*ServiceConfig{
+					"auth": {}, "post": {}, "comment": {},
+				},
+			}
+			err := cfg.validateRoutes()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to mention %q", err, tt.wantErr)
+			case tt.wantErr == "":
+				// Routes that pass validation must register without gin panicking
+				engine := gin.New()
+				for _, r := range tt.routes {
+					for _, m := range r.MethodList() {
+						engine.Handle(m, r.Path, func(*gin.Context) {})
+					}
+				}
+			}
+		})
+	}
+}
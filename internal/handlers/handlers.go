@@ -1,25 +1,36 @@
 package handlers
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"api-gateway/internal/apierror"
 	"api-gateway/internal/broker"
+	"api-gateway/internal/config"
 	"api-gateway/internal/models"
 )
 
 type MessageHandler struct {
 	rabbitClient *broker.RabbitMQClient
+	events       map[string]config.EventConfig
 	responseMap  map[string]chan *models.MessageResponse
 }
 
-func NewMessageHandler(rabbitClient *broker.RabbitMQClient) *MessageHandler {
+// NewMessageHandler publishes actions listed in events to their exchange;
+// other actions go straight to a queue
+func NewMessageHandler(rabbitClient *broker.RabbitMQClient, events []config.EventConfig) *MessageHandler {
+	byName := make(map[string]config.EventConfig, len(events))
+	for _, ev := range events {
+		byName[ev.Name] = ev
+	}
 	return &MessageHandler{
 		rabbitClient: rabbitClient,
+		events:       byName,
 		responseMap:  make(map[string]chan *models.MessageResponse),
 	}
 }
@@ -28,40 +39,54 @@ func NewMessageHandler(rabbitClient *broker.RabbitMQClient) *MessageHandler {
 func (h *MessageHandler) SendMessage(c *gin.Context) {
 	var req models.MessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.MessageResponse{
-			Status: "error",
-			Error:  "Invalid request format: " + err.Error(),
-		})
+		apierror.Abort(c, apierror.CodeBadRequest, "Invalid request format: "+err.Error())
 		return
 	}
 
 	// Create unique message ID
 	messageID := uuid.New().String()
 
+	// Correlate the message with the request that produced it
+	requestID := c.GetString("request_id")
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["request_id"] = requestID
+
 	queueMsg := models.QueueMessage{
 		ID:        messageID,
 		UserID:    req.UserID,
 		Action:    req.Action,
 		Payload:   req.Payload,
 		Timestamp: time.Now().Unix(),
-		Metadata:  req.Metadata,
+		Metadata:  metadata,
 	}
 
-	// Determine queue based on action
-	queueName := h.getQueueForAction(req.Action)
+	// Determine exchange or queue based on action
+	msg := h.messageForAction(req.Action, queueMsg)
 
 	// Publish message to RabbitMQ
-	err := h.rabbitClient.PublishMessage(queueName, queueMsg)
+	err := h.rabbitClient.Publish(c.Request.Context(), msg)
 	if err != nil {
-		log.Printf("Error publishing message: %v", err)
-		c.JSON(http.StatusInternalServerError, models.MessageResponse{
-			Status: "error",
-			Error:  "Failed to send message",
-		})
+		slog.ErrorContext(c.Request.Context(), "Failed to publish message",
+			"exchange", msg.Exchange, "routing_key", msg.RoutingKey, "message_id", messageID, "error", err)
+
+		var returned *broker.ReturnError
+		switch {
+		case errors.As(err, &returned):
+			apierror.Abort(c, apierror.CodeMessageUnroutable, "Message could not be routed to a queue")
+		case errors.Is(err, broker.ErrUnconfirmed):
+			// The broker may still have taken it, so this is not a definite failure
+			apierror.Abort(c, apierror.CodePublishUnconfirmed, "Message delivery not confirmed")
+		default:
+			apierror.Abort(c, apierror.CodeServiceUnavailable, "Failed to send message")
+		}
 		return
 	}
 
-	log.Printf("Message sent: %s to queue: %s", messageID, queueName)
+	slog.InfoContext(c.Request.Context(), "Message sent",
+		"message_id", messageID, "exchange", msg.Exchange, "routing_key", msg.RoutingKey)
 
 	c.JSON(http.StatusAccepted, models.MessageResponse{
 		Status:    "accepted",
@@ -80,14 +105,6 @@ func (h *MessageHandler) GetMessageStatus(c *gin.Context) {
 	})
 }
 
-// HealthCheck - service health check
-func (h *MessageHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"time":   time.Now().Unix(),
-	})
-}
-
 // GetQueueInfo - information about queues
 func (h *MessageHandler) GetQueueInfo(c *gin.Context) {
 	// Here you can get queue status information
@@ -97,6 +114,20 @@ func (h *MessageHandler) GetQueueInfo(c *gin.Context) {
 	})
 }
 
+// messageForAction publishes configured events to their exchange and
+// routes any other action to a queue through the default exchange
+func (h *MessageHandler) messageForAction(action string, body models.QueueMessage) broker.Message {
+	if ev, ok := h.events[action]; ok {
+		return broker.Message{
+			Exchange:   ev.Exchange,
+			RoutingKey: ev.RoutingKey,
+			Headers:    ev.Headers,
+			Body:       body,
+		}
+	}
+	return broker.Message{RoutingKey: h.getQueueForAction(action), Body: body}
+}
+
 func (h *MessageHandler) getQueueForAction(action string) string {
 	// Routing based on action
 	switch action {
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/broker"
+	"api-gateway/internal/config"
+)
+
+// benchQueue is declared auto-delete so benchmark runs leave nothing behind
+const benchQueue = "default_queue_bench"
+
+// benchBroker connects to the broker in RABBITMQ_URL, skipping without one
+func benchBroker(b *testing.B, channelMax int) *broker.RabbitMQClient {
+	b.Helper()
+	url := os.Getenv("RABBITMQ_URL")
+	if url == "" {
+		b.Skip("RABBITMQ_URL not set, skipping publish benchmark")
+	}
+
+	client := broker.NewRabbitMQClient(&config.RabbitMQConfig{
+		URL:                 url,
+		Heartbeat:           30,
+		ConnectionTimeout:   5 * time.Second,
+		ChannelMax:          channelMax,
+		ReconnectMinBackoff: 100 * time.Millisecond,
+		ReconnectMaxBackoff: time.Second,
+		PublisherConfirms:   true,
+		ConfirmTimeout:      5 * time.Second,
+		Mandatory:           true,
+		Queues:              []config.QueueConfig{{Name: benchQueue, AutoDelete: true}},
+	})
+	b.Cleanup(client.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.WaitConnected(ctx); err != nil {
+		b.Fatalf("connect to %s: %v", url, err)
+	}
+	return client
+}
+
+// BenchmarkSendMessageConcurrent measures confirmed publish throughput for
+// concurrent HTTP requests to SendMessage. A channel max of 2 leaves a
+// single publishing channel, like the client had before the channel pool.
+func BenchmarkSendMessageConcurrent(b *testing.B) {
+	for _, channelMax := range []int{2, 16, 100} {
+		b.Run("channel_max="+strconv.Itoa(channelMax), func(b *testing.B) {
+			client := benchBroker(b, channelMax)
+			h := NewMessageHandler(client, nil)
+			// Unknown actions go to default_queue; route them to the bench queue
+			h.events = map[string]config.EventConfig{
+				"bench": {Name: "bench", Exchange: "", RoutingKey: benchQueue},
+			}
+
+			gin.SetMode(gin.TestMode)
+			engine := gin.New()
+			engine.POST("/api/v1/messages", h.SendMessage)
+			gw := httptest.NewServer(engine)
+			b.Cleanup(gw.Close)
+
+			httpClient := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 256}}
+			b.Cleanup(httpClient.CloseIdleConnections)
+			body := `{"user_id":"1","action":"bench","payload":{"title":"hello"}}`
+
+			b.SetParallelism(16)
+			b.ResetTimer()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					resp, err := httpClient.Post(gw.URL+"/api/v1/messages", "application/json", strings.NewReader(body))
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					if resp.StatusCode != http.StatusAccepted {
+						b.Errorf("status = %d", resp.StatusCode)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "msgs/s")
+		})
+	}
+}
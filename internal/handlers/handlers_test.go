@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"api-gateway/internal/broker"
+	"api-gateway/internal/config"
+	"api-gateway/internal/models"
+)
+
+func TestMessageForAction(t *testing.T) {
+	h := NewMessageHandler(nil, []config.EventConfig{{
+		Name:       "post.created",
+		Exchange:   "post.events",
+		RoutingKey: "post.created",
+		Headers:    map[string]interface{}{"event-type": "post.created"},
+	}})
+	body := models.QueueMessage{ID: "1"}
+
+	tests := []struct {
+		action string
+		want   broker.Message
+	}{
+		{"post.created", broker.Message{
+			Exchange:   "post.events",
+			RoutingKey: "post.created",
+			Headers:    map[string]interface{}{"event-type": "post.created"},
+			Body:       body,
+		}},
+		{"login", broker.Message{RoutingKey: "user_actions", Body: body}},
+		{"send_notification", broker.Message{RoutingKey: "notifications", Body: body}},
+		{"unknown", broker.Message{RoutingKey: "default_queue", Body: body}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			if got := h.messageForAction(tt.action, body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messageForAction(%q) = %+v, want %+v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
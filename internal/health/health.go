@@ -0,0 +1,196 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Component statuses in a Report
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// Checker reports whether a dependency is usable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// ComponentStatus is the last result of one checker
+type ComponentStatus struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the readiness view returned by /readyz
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+	Time       int64                      `json:"time"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	checker  Checker
+
+	mu     sync.Mutex
+	result ComponentStatus
+}
+
+// Registry runs registered checkers with a per-check timeout and caches
+// results for cacheTTL, so frequent probes don't hammer dependencies.
+// Failing critical checkers make the gateway unready; failing non-critical
+// ones only mark it degraded.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	return &Registry{timeout: timeout, cacheTTL: cacheTTL}
+}
+
+// Register adds a named checker
+func (r *Registry) Register(name string, critical bool, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &check{name: name, critical: critical, checker: checker})
+}
+
+// SetDraining marks the gateway unready, used during graceful shutdown so
+// load balancers stop sending new traffic
+func (r *Registry) SetDraining() {
+	r.draining.Store(true)
+}
+
+// Check runs all checkers concurrently, reusing cached results
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentStatus, len(checks)),
+		Time:       time.Now().Unix(),
+	}
+
+	results := make([]ComponentStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	for i, chk := range checks {
+		res := results[i]
+		report.Components[chk.name] = res
+		if res.Status == StatusOK {
+			continue
+		}
+		if chk.critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	if r.draining.Load() {
+		report.Status = StatusFail
+		report.Components["shutdown"] = ComponentStatus{
+			Status:    StatusFail,
+			Critical:  true,
+			Error:     "gateway is shutting down",
+			CheckedAt: time.Now(),
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, chk *check) ComponentStatus {
+	// Holding the lock while checking collapses concurrent probes into one
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+
+	if !chk.result.CheckedAt.IsZero() && time.Since(chk.result.CheckedAt) < r.cacheTTL {
+		return chk.result
+	}
+
+	// The result is shared, so a caller going away must not fail the check
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := chk.checker.Check(ctx)
+	res := ComponentStatus{
+		Status:    StatusOK,
+		Critical:  chk.critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	chk.result = res
+	return res
+}
+
+// LivezHandler reports that the process is up and serving. It does not
+// check dependencies, so a broken dependency never gets the gateway restarted.
+func (r *Registry) LivezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": StatusOK,
+		"time":   time.Now().Unix(),
+	})
+}
+
+// ReadyzHandler returns only the overall status, with 503 when a critical
+// dependency is failing or the gateway is shutting down. It is served on the
+// public port, so component errors stay on the admin listener.
+func (r *Registry) ReadyzHandler(c *gin.Context) {
+	report := r.Check(c.Request.Context())
+	c.JSON(statusCode(report), gin.H{
+		"status": report.Status,
+		"time":   report.Time,
+	})
+}
+
+// ReportHandler returns the per-component report with the same status code
+// as ReadyzHandler
+func (r *Registry) ReportHandler(c *gin.Context) {
+	report := r.Check(c.Request.Context())
+	c.JSON(statusCode(report), report)
+}
+
+func statusCode(report Report) int {
+	if report.Status == StatusFail {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
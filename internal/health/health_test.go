@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errDown = errors.New("down")
+
+func status(err error) Checker {
+	return CheckerFunc(func(context.Context) error { return err })
+}
+
+func TestRegistryStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		critical    error
+		nonCritical error
+		want        string
+	}{
+		{"all healthy", nil, nil, StatusOK},
+		{"non-critical failing", nil, errDown, StatusDegraded},
+		{"critical failing", errDown, nil, StatusFail},
+		{"both failing", errDown, errDown, StatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(time.Second, 0)
+			r.Register("redis", true, status(tt.critical))
+			r.Register("rabbitmq", false, status(tt.nonCritical))
+
+			report := r.Check(context.Background())
+			if report.Status != tt.want {
+				t.Fatalf("status = %q, want %q", report.Status, tt.want)
+			}
+			if c := report.Components["rabbitmq"]; c.Critical || (tt.nonCritical != nil) != (c.Status == StatusFail) {
+				t.Errorf("rabbitmq component = %+v", c)
+			}
+			if c := report.Components["redis"]; tt.critical != nil && c.Error != "down" {
+				t.Errorf("redis error = %q, want down", c.Error)
+			}
+		})
+	}
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	r := NewRegistry(20*time.Millisecond, 0)
+	r.Register("slow", true, CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check took %v, want it bounded by the timeout", elapsed)
+	}
+	if report.Status != StatusFail || report.Components["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("report = %+v, want slow failing with a deadline", report)
+	}
+}
+
+func TestRegistryCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry(time.Second, 50*time.Millisecond)
+	r.Register("redis", true, CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}))
+
+	// Concurrent probes share one check
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Check(context.Background())
+		}()
+	}
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("checker called %d times, want 1", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	r.Check(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("checker called %d times after the TTL, want 2", got)
+	}
+}
+
+func TestRegistryIgnoresCallerCancellation(t *testing.T) {
+	r := NewRegistry(time.Second, time.Minute)
+	r.Register("redis", true, CheckerFunc(func(ctx context.Context) error { return ctx.Err() }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := r.Check(ctx); report.Status != StatusOK {
+		t.Fatalf("status = %q, want the canceled caller not to fail the shared check", report.Status)
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRegistry(time.Second, 0)
+	r.Register("rabbitmq", false, status(errDown))
+
+	engine := gin.New()
+	engine.GET("/livez", r.LivezHandler)
+	engine.GET("/readyz", r.ReadyzHandler)
+	engine.GET("/admin/health", r.ReportHandler)
+
+	get := func(path string) (int, Report, string) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		json.Unmarshal(w.Body.Bytes(), &report)
+		return w.Code, report, w.Body.String()
+	}
+
+	// A degraded gateway still takes traffic
+	code, report, body := get("/readyz")
+	if code != http.StatusOK || report.Status != StatusDegraded {
+		t.Fatalf("readyz = %d %q, want 200 degraded", code, report.Status)
+	}
+	// The public probe must not expose dependency errors
+	if report.Components != nil || strings.Contains(body, errDown.Error()) {
+		t.Errorf("readyz body = %s, want the status only", body)
+	}
+	code, report, _ = get("/admin/health")
+	if code != http.StatusOK || report.Components["rabbitmq"].Error != errDown.Error() {
+		t.Fatalf("admin health = %d %+v, want 200 with the rabbitmq error", code, report)
+	}
+
+	r.SetDraining()
+	if code, report, _ := get("/readyz"); code != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Fatalf("readyz while draining = %d %q, want 503 fail", code, report.Status)
+	}
+	code, report, _ = get("/admin/health")
+	if code != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Fatalf("admin health while draining = %d %q, want 503 fail", code, report.Status)
+	}
+	if _, ok := report.Components["shutdown"]; !ok {
+		t.Error("draining report has no shutdown component")
+	}
+
+	if code, _, _ := get("/livez"); code != http.StatusOK {
+		t.Fatalf("livez while draining = %d, want 200", code)
+	}
+}
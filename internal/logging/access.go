@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+// AccessLog writes one structured record per request. Successful requests
+// on routes listed in SampleRates are sampled; 4xx/5xx are always logged.
+// Request headers are included at debug level, with sensitive ones redacted.
+func AccessLog(cfg *config.LoggingConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if rate, ok := cfg.SampleRates[route]; ok && status < http.StatusBadRequest && rand.Float64() >= rate {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		ctx := c.Request.Context()
+		logger := slog.Default()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", RedactURL(c.Request.URL, cfg.RedactQueryParams)),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("upstream_service", c.GetString("upstream_service")),
+			slog.String("upstream", c.GetString("upstream")),
+			slog.String("user_id", c.GetString("x_user_id")),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("headers", RedactHeaders(c.Request.Header, cfg.RedactHeaders)))
+		}
+
+		logger.LogAttrs(ctx, level, "Request", attrs...)
+	}
+}
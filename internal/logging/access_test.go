@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/middleware"
+)
+
+// captureLogs routes the default logger to a buffer at level for the test
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func accessEngine(cfg *config.LoggingConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.RequestID(), AccessLog(cfg))
+	engine.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/api/v1/posts/:id", func(c *gin.Context) {
+		switch c.Param("id") {
+		case "missing":
+			c.Status(http.StatusNotFound)
+		case "fail":
+			c.Status(http.StatusBadGateway)
+		default:
+			c.Status(http.StatusOK)
+		}
+	})
+	return engine
+}
+
+func TestAccessLogLevelsAndSampling(t *testing.T) {
+	cfg := &config.LoggingConfig{SampleRates: map[string]float64{"/health": 0}}
+	engine := accessEngine(cfg)
+
+	tests := []struct {
+		path      string
+		wantLevel string
+	}{
+		// Sampled out entirely at a rate of 0
+		{"/health", ""},
+		{"/api/v1/posts/1", "INFO"},
+		{"/api/v1/posts/missing", "WARN"},
+		{"/api/v1/posts/fail", "ERROR"},
+	}
+	for _, tt := range tests {
+		buf := captureLogs(t, slog.LevelInfo)
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		recs := records(t, buf)
+		if tt.wantLevel == "" {
+			if len(recs) != 0 {
+				t.Errorf("%s: logged %v, want it sampled out", tt.path, recs)
+			}
+			continue
+		}
+		if len(recs) != 1 {
+			t.Fatalf("%s: got %d records, want 1", tt.path, len(recs))
+		}
+		if recs[0]["level"] != tt.wantLevel {
+			t.Errorf("%s: level = %v, want %s", tt.path, recs[0]["level"], tt.wantLevel)
+		}
+		if recs[0]["route"] != "/api/v1/posts/:id" {
+			t.Errorf("%s: route = %v", tt.path, recs[0]["route"])
+		}
+		if _, ok := recs[0]["headers"]; ok {
+			t.Errorf("%s: headers logged above debug level", tt.path)
+		}
+	}
+}
+
+func TestAccessLogSamplingKeepsErrors(t *testing.T) {
+	cfg := &config.LoggingConfig{SampleRates: map[string]float64{"/api/v1/posts/:id": 0}}
+	engine := accessEngine(cfg)
+
+	buf := captureLogs(t, slog.LevelInfo)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/posts/1", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/posts/missing", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/posts/fail", nil))
+
+	recs := records(t, buf)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want the 4xx and 5xx only", len(recs))
+	}
+	for _, rec := range recs {
+		if status := rec["status"].(float64); status < http.StatusBadRequest {
+			t.Errorf("sampled-out status %v was logged", status)
+		}
+	}
+}
+
+func TestAccessLogRedactsAtDebug(t *testing.T) {
+	cfg := &config.LoggingConfig{
+		RedactHeaders:     []string{"Authorization"},
+		RedactQueryParams: []string{"token"},
+	}
+	engine := accessEngine(cfg)
+
+	buf := captureLogs(t, slog.LevelDebug)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/posts/1?token=secret&page=2", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Request-ID", "req-1")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("log contains a secret: %s", buf.String())
+	}
+	recs := records(t, buf)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if rec["path"] != "/api/v1/posts/1?page=2&token=REDACTED" {
+		t.Errorf("path = %v", rec["path"])
+	}
+	headers, _ := rec["headers"].(map[string]any)
+	if headers["Authorization"] != redacted {
+		t.Errorf("headers = %v, want Authorization redacted", headers)
+	}
+	if rec["request_id"] != "req-1" {
+		t.Errorf("request_id = %v, want req-1", rec["request_id"])
+	}
+}
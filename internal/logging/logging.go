@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/requestid"
+)
+
+// Setup installs the default slog logger from LOG_LEVEL and LOG_FORMAT.
+// Output from the standard log package is routed through it as well.
+func Setup(cfg *config.Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.LogLevel)}
+
+	var handler slog.Handler
+	if cfg.Logging.Format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	logger := slog.New(contextHandler{handler}).With("service", cfg.AppName)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// ParseLevel maps LOG_LEVEL to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler adds the request ID to records logged with a request context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// RedactURL returns the path and query of u with sensitive query
+// parameters replaced
+func RedactURL(u *url.URL, params []string) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	query := u.Query()
+	for key := range query {
+		if containsFold(params, key) {
+			query[key] = []string{redacted}
+		}
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// RedactHeaders flattens h for logging with sensitive headers replaced
+func RedactHeaders(h http.Header, names []string) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		if containsFold(names, key) {
+			out[key] = redacted
+			continue
+		}
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func containsFold(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
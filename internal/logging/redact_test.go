@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	params := []string{"token", "api_key"}
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"/api/v1/posts", "/api/v1/posts"},
+		{"/api/v1/posts?page=2", "/api/v1/posts?page=2"},
+		{"/callback?token=secret&page=2", "/callback?page=2&token=REDACTED"},
+		{"/callback?API_KEY=a&api_key=b", "/callback?API_KEY=REDACTED&api_key=REDACTED"},
+		{"/callback?token=a&token=b", "/callback?token=REDACTED"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := RedactURL(u, params); got != tt.want {
+			t.Errorf("RedactURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"session=secret"},
+		"Accept":        {"text/html", "application/json"},
+	}
+	got := RedactHeaders(h, []string{"authorization", "COOKIE"})
+
+	want := map[string]string{
+		"Authorization": redacted,
+		"Cookie":        redacted,
+		"Accept":        "text/html, application/json",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
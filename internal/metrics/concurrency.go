@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"api-gateway/internal/concurrency"
+)
+
+var (
+	concurrencyLimitDesc = prometheus.NewDesc(namespace+"_concurrency_limit",
+		"Current concurrency limit.", []string{"limiter"}, nil)
+	concurrencyInFlightDesc = prometheus.NewDesc(namespace+"_concurrency_in_flight",
+		"Requests holding a concurrency slot.", []string{"limiter"}, nil)
+	concurrencyShedDesc = prometheus.NewDesc(namespace+"_concurrency_shed_total",
+		"Requests shed by the concurrency limiter.", []string{"limiter", "priority"}, nil)
+)
+
+// concurrencyCollector reads limiter stats at scrape time
+type concurrencyCollector struct {
+	stats func() []concurrency.Stats
+}
+
+// RegisterConcurrency exposes the limiters returned by stats
+func RegisterConcurrency(stats func() []concurrency.Stats) {
+	Registry.MustRegister(&concurrencyCollector{stats: stats})
+}
+
+func (c *concurrencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- concurrencyLimitDesc
+	ch <- concurrencyInFlightDesc
+	ch <- concurrencyShedDesc
+}
+
+func (c *concurrencyCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.stats() {
+		ch <- prometheus.MustNewConstMetric(concurrencyLimitDesc, prometheus.GaugeValue, float64(s.Limit), s.Name)
+		ch <- prometheus.MustNewConstMetric(concurrencyInFlightDesc, prometheus.GaugeValue, float64(s.InFlight), s.Name)
+		ch <- prometheus.MustNewConstMetric(concurrencyShedDesc, prometheus.CounterValue, float64(s.ShedLow), s.Name, "low")
+		ch <- prometheus.MustNewConstMetric(concurrencyShedDesc, prometheus.CounterValue, float64(s.ShedNormal), s.Name, "normal")
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gateway"
+
+// Registry holds every gateway collector. A dedicated registry keeps the
+// exposed series to what the gateway registers plus Go runtime metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled by the gateway.",
+	}, []string{"route", "method", "status", "service"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests, including the upstream call.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status", "service"})
+
+	RequestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	}, []string{"route", "method", "service"})
+
+	UpstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed upstream calls by error kind.",
+	}, []string{"service", "kind"})
+
+	BreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per service (0 closed, 1 open, 2 half-open).",
+	}, []string{"service"})
+
+	RabbitMQPublishes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_publish_total",
+		Help:      "Messages published to RabbitMQ by destination exchange (or queue for the default exchange) and result.",
+	}, []string{"destination", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestsTotal,
+		RequestDuration,
+		RequestsInFlight,
+		UpstreamErrors,
+		BreakerState,
+		RabbitMQPublishes,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Publish records the outcome of a RabbitMQ publish, e.g. "success",
+// "returned" or "nacked"
+func Publish(destination, result string) {
+	RabbitMQPublishes.WithLabelValues(destination, result).Inc()
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"api-gateway/internal/concurrency"
+	"api-gateway/internal/config"
+)
+
+func TestMiddlewareLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	routes := []config.RouteConfig{
+		{Path: "/api/v1/posts/:id", Methods: []string{"GET"}, Service: "post"},
+	}
+	engine := gin.New()
+	engine.Use(Middleware(routes))
+	engine.GET("/api/v1/posts/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/posts/1"},
+		{http.MethodGet, "/api/v1/posts/2"},
+		{http.MethodGet, "/api/v1/posts/missing"},
+		{http.MethodGet, "/random/path/123"},
+		{"PURGE", "/api/v1/posts/1"},
+	} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	tests := []struct {
+		labels []string
+		want   float64
+	}{
+		// Concrete IDs collapse into the route template
+		{[]string{"/api/v1/posts/:id", "GET", "2xx", "post"}, 2},
+		{[]string{"/api/v1/posts/:id", "GET", "4xx", "post"}, 1},
+		{[]string{"unmatched", "GET", "4xx", ""}, 1},
+		{[]string{"unmatched", "OTHER", "4xx", ""}, 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(RequestsTotal.WithLabelValues(tt.labels...)); got != tt.want {
+			t.Errorf("requests%v = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+
+	if got := testutil.ToFloat64(RequestsInFlight.WithLabelValues("/api/v1/posts/:id", "GET", "post")); got != 0 {
+		t.Errorf("in flight after requests = %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(RequestDuration, namespace+"_http_request_duration_seconds"); got != 4 {
+		t.Errorf("duration series = %d, want 4", got)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	Publish("notifications", "success")
+	Publish("notifications", "success")
+	Publish("notifications", "returned")
+
+	if got := testutil.ToFloat64(RabbitMQPublishes.WithLabelValues("notifications", "success")); got != 2 {
+		t.Errorf("successful publishes = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(RabbitMQPublishes.WithLabelValues("notifications", "returned")); got != 1 {
+		t.Errorf("returned publishes = %v, want 1", got)
+	}
+}
+
+func TestHandlerExposesConcurrency(t *testing.T) {
+	RegisterConcurrency(func() []concurrency.Stats {
+		return []concurrency.Stats{{Name: "global", Limit: 100, InFlight: 3, ShedLow: 7}}
+	})
+
+	resp := httptest.NewRecorder()
+	Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`gateway_concurrency_limit{limiter="global"} 100`,
+		`gateway_concurrency_in_flight{limiter="global"} 3`,
+		`gateway_concurrency_shed_total{limiter="global",priority="low"} 7`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exposition is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Middleware records request count, latency and in-flight requests. The
+// route label is the matched route template so unmatched paths cannot blow
+// up label cardinality.
+func Middleware(routes []config.RouteConfig) gin.HandlerFunc {
+	services := make(map[string]string)
+	for _, route := range routes {
+		for _, method := range route.MethodList() {
+			services[method+" "+route.Path] = route.Service
+		}
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+		service := services[method+" "+route]
+
+		inFlight := RequestsInFlight.WithLabelValues(route, method, service)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		status := strconv.Itoa(c.Writer.Status()/100) + "xx"
+		RequestsTotal.WithLabelValues(route, method, status, service).Inc()
+		RequestDuration.WithLabelValues(route, method, status, service).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+// CORSMiddleware applies the CORS policy from CORSConfig. AllowOrigins
+// entries may be exact origins, "*", wildcard subdomain patterns such as
+// "https://*.example.com" or regular expressions prefixed with "regex:",
+// which must match the whole origin.
+type CORSMiddleware struct {
+	config       *config.CORSConfig
+	anyOrigin    bool
+	origins      map[string]bool
+	patterns     []*regexp.Regexp
+	anyHeader    bool
+	allowHeaders string
+	expose       string
+	maxAge       string
+	routeMethods func(path string) []string
+}
+
+// NewCORSMiddleware builds the CORS middleware. routeMethods returns the
+// methods served for a request path and scopes preflight answers to the route.
+func NewCORSMiddleware(cfg *config.CORSConfig, routeMethods func(path string) []string) (*CORSMiddleware, error) {
+	m := &CORSMiddleware{
+		config:       cfg,
+		origins:      make(map[string]bool),
+		expose:       strings.Join(cfg.ExposeHeaders, ", "),
+		maxAge:       strconv.Itoa(int(cfg.MaxAge.Seconds())),
+		routeMethods: routeMethods,
+	}
+
+	for _, origin := range cfg.AllowOrigins {
+		switch {
+		case origin == "*":
+			m.anyOrigin = true
+		case strings.HasPrefix(origin, "regex:"):
+			re, err := regexp.Compile("^(?:" + strings.TrimPrefix(origin, "regex:") + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid CORS origin pattern %q: %w", origin, err)
+			}
+			m.patterns = append(m.patterns, re)
+		case strings.Contains(origin, "*"):
+			expr := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[a-z0-9.-]+`)
+			m.patterns = append(m.patterns, regexp.MustCompile("^"+expr+"$"))
+		default:
+			m.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	// Echoing any origin with credentials would let every site make
+	// authenticated requests on behalf of the user
+	if m.anyOrigin && cfg.AllowCredentials {
+		return nil, fmt.Errorf("CORS origin \"*\" cannot be combined with allowed credentials")
+	}
+
+	for _, h := range cfg.AllowHeaders {
+		if h == "*" {
+			m.anyHeader = true
+		}
+	}
+	m.allowHeaders = strings.Join(cfg.AllowHeaders, ", ")
+
+	return m, nil
+}
+
+func (m *CORSMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Responses differ by origin, including requests without one, so
+		// caches must not serve a response cached for one origin to another
+		header := c.Writer.Header()
+		if !m.anyOrigin {
+			header.Add("Vary", "Origin")
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			m.handlePreflight(c, origin)
+			return
+		}
+
+		if m.allowed(origin) {
+			m.setOriginHeaders(header, origin)
+			if m.expose != "" {
+				header.Set("Access-Control-Expose-Headers", m.expose)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func (m *CORSMiddleware) handlePreflight(c *gin.Context, origin string) {
+	methods := m.routeMethods(c.Request.URL.Path)
+	if len(methods) == 0 {
+		// Not a gateway route, let it 404
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	methods = m.allowedMethods(methods)
+	requested := strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+	if !m.allowed(origin) || !contains(methods, requested) {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	m.setOriginHeaders(header, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if m.anyHeader {
+		if requestedHeaders := c.GetHeader("Access-Control-Request-Headers"); requestedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", requestedHeaders)
+		}
+	} else if m.allowHeaders != "" {
+		header.Set("Access-Control-Allow-Headers", m.allowHeaders)
+	}
+	if m.config.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", m.maxAge)
+	}
+
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// setOriginHeaders answers "*" when any origin is allowed and echoes the
+// matched origin otherwise
+func (m *CORSMiddleware) setOriginHeaders(header http.Header, origin string) {
+	if m.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if m.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (m *CORSMiddleware) allowed(origin string) bool {
+	if m.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if m.origins[origin] {
+		return true
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedMethods intersects the route's methods with the configured ones
+func (m *CORSMiddleware) allowedMethods(routeMethods []string) []string {
+	if contains(m.config.AllowMethods, "*") {
+		return routeMethods
+	}
+	methods := make([]string, 0, len(routeMethods))
+	for _, method := range routeMethods {
+		if contains(m.config.AllowMethods, method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestNewCORSMiddlewareRejectsAnyOriginWithCredentials(t *testing.T) {
+	cfg := &config.CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}
+	if _, err := NewCORSMiddleware(cfg, nil); err == nil {
+		t.Fatal("expected an error for \"*\" with credentials")
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		credentials bool
+		origin      string
+		wantOrigin  string
+		wantCreds   string
+	}{
+		{"any origin", []string{"*"}, false, "https://evil.net", "*", ""},
+		{"exact origin", []string{"https://a.com"}, true, "https://a.com", "https://a.com", "true"},
+		{"exact origin mismatch", []string{"https://a.com"}, true, "https://b.com", "", ""},
+		{"subdomain pattern", []string{"https://*.a.com"}, true, "https://app.a.com", "https://app.a.com", "true"},
+		{"subdomain pattern suffix", []string{"https://*.a.com"}, true, "https://app.a.com.evil.net", "", ""},
+		{"regex", []string{`regex:https://a\.com`}, false, "https://a.com", "https://a.com", ""},
+		{"regex is anchored at the end", []string{`regex:https://a\.com`}, false, "https://a.com.evil.net", "", ""},
+		{"regex is anchored at the start", []string{`regex:a\.com`}, false, "https://evil.net/a.com", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors, err := NewCORSMiddleware(&config.CORSConfig{
+				AllowOrigins:     tt.origins,
+				AllowCredentials: tt.credentials,
+			}, func(string) []string { return []string{http.MethodGet} })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			engine := gin.New()
+			engine.Use(cors.Handler())
+			engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			engine.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+		})
+	}
+}
+
+func TestCORSVaryOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		origins  []string
+		origin   string
+		wantVary bool
+	}{
+		{"allowed origin", []string{"https://a.com"}, "https://a.com", true},
+		{"disallowed origin", []string{"https://a.com"}, "https://b.com", true},
+		{"no origin", []string{"https://a.com"}, "", true},
+		{"any origin", []string{"*"}, "https://a.com", false},
+		{"any origin without origin", []string{"*"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors, err := NewCORSMiddleware(&config.CORSConfig{AllowOrigins: tt.origins},
+				func(string) []string { return []string{http.MethodGet} })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			engine := gin.New()
+			engine.Use(cors.Handler())
+			engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			engine.ServeHTTP(w, req)
+
+			vary := w.Header().Values("Vary")
+			if got := contains(vary, "Origin"); got != tt.wantVary {
+				t.Errorf("Vary = %q, want Origin %v", vary, tt.wantVary)
+			}
+		})
+	}
+}
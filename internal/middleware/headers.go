@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StripReservedHeaders removes headers that only the gateway may set, so a
+// client cannot spoof identity headers on routes that skip JWT validation
+func StripReservedHeaders(headers []string) gin.HandlerFunc {
+	reserved := make([]string, 0, len(headers))
+	for _, h := range headers {
+		reserved = append(reserved, http.CanonicalHeaderKey(h))
+	}
+
+	return func(c *gin.Context) {
+		for _, h := range reserved {
+			c.Request.Header.Del(h)
+		}
+		c.Next()
+	}
+}
@@ -2,12 +2,13 @@ package middleware
 
 import (
 	"crypto/sha256"
-	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"api-gateway/internal/apierror"
 )
 
 type JWTMiddleware struct {
@@ -22,13 +23,13 @@ func (m *JWTMiddleware) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+			apierror.Abort(c, apierror.CodeUnauthorized, "missing authorization header")
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
+			apierror.Abort(c, apierror.CodeUnauthorized, "invalid authorization header format")
 			return
 		}
 
@@ -41,25 +42,27 @@ func (m *JWTMiddleware) Handler() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			apierror.Abort(c, apierror.CodeUnauthorized, "invalid token")
 			return
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			apierror.Abort(c, apierror.CodeUnauthorized, "invalid token claims")
 			return
 		}
 
 		userID := extractUserID(claims)
 		if userID == 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user id not found in token"})
+			apierror.Abort(c, apierror.CodeUnauthorized, "user id not found in token")
 			return
 		}
 
 		c.Set("user_id", userID)
 		c.Set("x_user_id", strconv.FormatInt(userID, 10))
 		c.Set("x_username", extractUsername(claims))
+		c.Set("x_user_roles", extractRoles(claims))
+		c.Set("jwt_claims", claims)
 
 		c.Next()
 	}
@@ -115,3 +118,27 @@ func extractUsername(claims jwt.MapClaims) string {
 	}
 	return ""
 }
+
+func extractRoles(claims jwt.MapClaims) []string {
+	var roles []string
+	add := func(v interface{}) {
+		switch r := v.(type) {
+		case string:
+			roles = append(roles, strings.FieldsFunc(r, func(c rune) bool { return c == ',' || c == ' ' })...)
+		case []interface{}:
+			for _, item := range r {
+				if s, ok := item.(string); ok {
+					roles = append(roles, s)
+				}
+			}
+		}
+	}
+
+	add(claims["roles"])
+	add(claims["role"])
+	// Keycloak style realm roles
+	if realm, ok := claims["realm_access"].(map[string]interface{}); ok {
+		add(realm["roles"])
+	}
+	return roles
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/apierror"
+)
+
+// Recovery turns handler panics into the JSON error envelope. The
+// http.ErrAbortHandler panic raised by the reverse proxy when an upstream
+// fails mid-response is re-raised so net/http resets the connection
+// instead of completing a truncated response.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			slog.ErrorContext(c.Request.Context(), "Panic recovered",
+				"error", err, "method", c.Request.Method, "path", c.Request.URL.Path,
+				"stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			apierror.Abort(c, apierror.CodeInternal, "internal server error")
+		}()
+		c.Next()
+	}
+}
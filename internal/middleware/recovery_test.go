@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecovery(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Recovery())
+	engine.GET("/before", func(c *gin.Context) {
+		panic("boom")
+	})
+	engine.GET("/after", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	engine.GET("/abort", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/before", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"internal_error"`) {
+		t.Errorf("panic before writing: %d %s, want the 500 envelope", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/after", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("panic after writing: %d %q, want the written response untouched", w.Code, w.Body)
+	}
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler re-raised", err)
+		}
+	}()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	t.Error("http.ErrAbortHandler was swallowed")
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/requestid"
+)
+
+// RequestID reuses the client's X-Request-ID when valid or generates one.
+// The ID is stored in the gin and request contexts, forwarded upstream in
+// the request headers and returned in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set("request_id", id)
+		c.Request.Header.Set(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+
+		c.Next()
+	}
+}
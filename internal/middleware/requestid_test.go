@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{"reuses valid id", "abc-123", true},
+		{"generates when missing", "", false},
+		{"replaces id with spaces", "abc 123", false},
+		{"replaces id with control characters", "abc\x01", false},
+		{"replaces overlong id", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded, fromContext, fromGin string
+			engine := gin.New()
+			engine.Use(RequestID())
+			engine.GET("/", func(c *gin.Context) {
+				forwarded = c.Request.Header.Get(requestid.Header)
+				fromContext = requestid.FromContext(c.Request.Context())
+				fromGin = c.GetString("request_id")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestid.Header, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			got := w.Result().Header.Values(requestid.Header)
+			if len(got) != 1 {
+				t.Fatalf("response %s = %q, want exactly one value", requestid.Header, got)
+			}
+			id := got[0]
+			if tt.wantKept && id != tt.incoming {
+				t.Errorf("id = %q, want %q", id, tt.incoming)
+			}
+			if !tt.wantKept && (id == tt.incoming || !requestid.Valid(id)) {
+				t.Errorf("id = %q, want a new valid id", id)
+			}
+			if forwarded != id || fromContext != id || fromGin != id {
+				t.Errorf("forwarded %q, context %q, gin %q, want %q", forwarded, fromContext, fromGin, id)
+			}
+		})
+	}
+}
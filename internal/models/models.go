@@ -22,3 +22,16 @@ type QueueMessage struct {
 	Timestamp int64       `json:"timestamp"`
 	Metadata  interface{} `json:"metadata"`
 }
+
+// ErrorResponse is the envelope for every error returned by the gateway
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
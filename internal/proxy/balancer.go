@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"api-gateway/internal/config"
+)
+
+// Upstream is a single instance of a backend service
+type Upstream struct {
+	URL    *url.URL
+	Weight int
+
+	active int64
+	health upstreamHealth
+}
+
+// Active returns the number of in-flight requests to the upstream
+func (u *Upstream) Active() int64 {
+	return atomic.LoadInt64(&u.active)
+}
+
+func (u *Upstream) acquire() { atomic.AddInt64(&u.active, 1) }
+func (u *Upstream) release() { atomic.AddInt64(&u.active, -1) }
+
+// available returns the upstreams that may receive traffic. When every
+// instance is down all of them are returned, so the gateway fails open
+// instead of rejecting everything.
+func available(upstreams []*Upstream) []*Upstream {
+	ok := make([]*Upstream, 0, len(upstreams))
+	for _, u := range upstreams {
+		if u.Available() {
+			ok = append(ok, u)
+		}
+	}
+	if len(ok) == 0 {
+		return upstreams
+	}
+	return ok
+}
+
+// Balancer picks an upstream for a request. The key is used by
+// hash-based strategies to keep a client pinned to the same instance.
+type Balancer interface {
+	Next(key string) *Upstream
+}
+
+func newUpstreams(svc *config.ServiceConfig) ([]*Upstream, error) {
+	upstreams := make([]*Upstream, 0, len(svc.Upstreams))
+	for _, u := range svc.Upstreams {
+		parsed, err := url.Parse(u.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream URL %q: %w", u.URL, err)
+		}
+		upstreams = append(upstreams, &Upstream{
+			URL:    parsed,
+			Weight: u.Weight,
+			health: upstreamHealth{healthy: true},
+		})
+	}
+	return upstreams, nil
+}
+
+func newBalancer(strategy string, upstreams []*Upstream) Balancer {
+	switch strategy {
+	case "least-connections":
+		return &leastConnections{upstreams: upstreams}
+	case "consistent-hash":
+		return newConsistentHash(upstreams)
+	default:
+		return newWeightedRoundRobin(upstreams)
+	}
+}
+
+// weightedRoundRobin implements nginx's smooth weighted round-robin
+type weightedRoundRobin struct {
+	mu        sync.Mutex
+	upstreams []*Upstream
+	current   map[*Upstream]int
+}
+
+func newWeightedRoundRobin(upstreams []*Upstream) *weightedRoundRobin {
+	return &weightedRoundRobin{
+		upstreams: upstreams,
+		current:   make(map[*Upstream]int, len(upstreams)),
+	}
+}
+
+func (b *weightedRoundRobin) Next(_ string) *Upstream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	candidates := available(b.upstreams)
+	var best *Upstream
+	total := 0
+	for _, u := range candidates {
+		b.current[u] += u.Weight
+		total += u.Weight
+		if best == nil || b.current[u] > b.current[best] {
+			best = u
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	b.current[best] -= total
+	return best
+}
+
+// leastConnections picks the upstream with the fewest in-flight requests
+// relative to its weight
+type leastConnections struct {
+	upstreams []*Upstream
+}
+
+func (b *leastConnections) Next(_ string) *Upstream {
+	var best *Upstream
+	for _, u := range available(b.upstreams) {
+		// Compare active/weight without division
+		if best == nil || u.Active()*int64(best.Weight) < best.Active()*int64(u.Weight) {
+			best = u
+		}
+	}
+	return best
+}
+
+// consistentHash maps keys onto a ring of virtual nodes, weighted per upstream
+type consistentHash struct {
+	ring  []uint32
+	nodes map[uint32]*Upstream
+}
+
+const virtualNodesPerWeight = 100
+
+func newConsistentHash(upstreams []*Upstream) *consistentHash {
+	b := &consistentHash{nodes: make(map[uint32]*Upstream)}
+	for _, u := range upstreams {
+		for i := 0; i < u.Weight*virtualNodesPerWeight; i++ {
+			h := crc32.ChecksumIEEE([]byte(u.URL.String() + "#" + strconv.Itoa(i)))
+			if _, exists := b.nodes[h]; exists {
+				continue
+			}
+			b.nodes[h] = u
+			b.ring = append(b.ring, h)
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i] < b.ring[j] })
+	return b
+}
+
+func (b *consistentHash) Next(key string) *Upstream {
+	if len(b.ring) == 0 {
+		return nil
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= h })
+
+	// Walk the ring clockwise past instances that are down
+	for n := 0; n < len(b.ring); n++ {
+		if u := b.nodes[b.ring[(start+n)%len(b.ring)]]; u.Available() {
+			return u
+		}
+	}
+	return b.nodes[b.ring[start%len(b.ring)]]
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway/internal/config"
+)
+
+func testUpstreams(t *testing.T, weights ...int) []*Upstream {
+	t.Helper()
+	cfg := &config.ServiceConfig{}
+	for i, w := range weights {
+		cfg.Upstreams = append(cfg.Upstreams, config.UpstreamConfig{
+			URL:    "http://10.0.0." + strconv.Itoa(i+1) + ":8080",
+			Weight: w,
+		})
+	}
+	upstreams, err := newUpstreams(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return upstreams
+}
+
+func upstreamName(u *Upstream) string {
+	if u == nil {
+		return "<nil>"
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(u.URL.Host, "10.0.0."), ":8080")
+}
+
+func eject(u *Upstream) {
+	u.health.mu.Lock()
+	u.health.ejectedUntil = time.Now().Add(time.Minute)
+	u.health.mu.Unlock()
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	upstreams := testUpstreams(t, 5, 1, 1)
+	b := newWeightedRoundRobin(upstreams)
+
+	// nginx's smooth sequence for weights 5, 1, 1
+	var got []string
+	for i := 0; i < 7; i++ {
+		got = append(got, upstreamName(b.Next("")))
+	}
+	if want := "1 1 2 1 3 1 1"; strings.Join(got, " ") != want {
+		t.Fatalf("sequence = %v, want %s", got, want)
+	}
+
+	// Down instances are skipped
+	eject(upstreams[0])
+	counts := map[string]int{}
+	for i := 0; i < 10; i++ {
+		counts[upstreamName(b.Next(""))]++
+	}
+	if counts["1"] != 0 || counts["2"] != 5 || counts["3"] != 5 {
+		t.Fatalf("counts with instance 1 ejected = %v, want 2 and 3 evenly", counts)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	upstreams := testUpstreams(t, 1, 1, 2)
+	b := &leastConnections{upstreams: upstreams}
+
+	// Idle: the first instance wins ties
+	if got := upstreamName(b.Next("")); got != "1" {
+		t.Fatalf("idle pick = %s, want 1", got)
+	}
+
+	upstreams[0].acquire()
+	upstreams[1].acquire()
+	upstreams[2].acquire()
+	upstreams[2].acquire()
+	// 1/1, 1/1 and 2/2 in flight relative to weight: still tied
+	if got := upstreamName(b.Next("")); got != "1" {
+		t.Fatalf("pick = %s, want 1", got)
+	}
+
+	upstreams[0].acquire()
+	// Instance 1 now has 2/1, so instance 2 is least loaded
+	if got := upstreamName(b.Next("")); got != "2" {
+		t.Fatalf("pick = %s, want 2", got)
+	}
+
+	eject(upstreams[1])
+	if got := upstreamName(b.Next("")); got != "3" {
+		t.Fatalf("pick with instance 2 ejected = %s, want 3", got)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	upstreams := testUpstreams(t, 1, 1, 1)
+	b := newConsistentHash(upstreams)
+
+	keys := make([]string, 200)
+	pinned := make(map[string]*Upstream, len(keys))
+	counts := map[string]int{}
+	for i := range keys {
+		keys[i] = "user-" + strconv.Itoa(i)
+		pinned[keys[i]] = b.Next(keys[i])
+		counts[upstreamName(pinned[keys[i]])]++
+	}
+
+	// Sticky and spread over every instance
+	for _, key := range keys {
+		if b.Next(key) != pinned[key] {
+			t.Fatalf("key %s moved between calls", key)
+		}
+	}
+	if len(counts) != 3 {
+		t.Fatalf("distribution = %v, want all instances used", counts)
+	}
+
+	// Only the keys of an ejected instance move
+	eject(upstreams[0])
+	for _, key := range keys {
+		got := b.Next(key)
+		if got == upstreams[0] {
+			t.Fatalf("key %s still routed to the ejected instance", key)
+		}
+		if pinned[key] != upstreams[0] && got != pinned[key] {
+			t.Fatalf("key %s moved from %s to %s", key, upstreamName(pinned[key]), upstreamName(got))
+		}
+	}
+
+	// Unhealthy instances are skipped the same way
+	upstreams[1].health.mu.Lock()
+	upstreams[1].health.healthy = false
+	upstreams[1].health.mu.Unlock()
+	for _, key := range keys {
+		if got := b.Next(key); got != upstreams[2] {
+			t.Fatalf("key %s routed to %s, want the only available instance", key, upstreamName(got))
+		}
+	}
+
+	// With nothing available the key's own instance is used (fail open)
+	eject(upstreams[2])
+	for _, key := range keys[:20] {
+		if got := b.Next(key); got != pinned[key] {
+			t.Fatalf("key %s routed to %s with all instances down, want %s", key, upstreamName(got), upstreamName(pinned[key]))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"api-gateway/internal/config"
+)
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerEvent is emitted whenever a service's circuit breaker changes state
+type BreakerEvent struct {
+	Service string
+	From    BreakerState
+	To      BreakerState
+	Time    time.Time
+}
+
+// circuitBreaker is a closed/open/half-open breaker counting failures over
+// a fixed window. While open every request is rejected; after OpenDuration
+// up to HalfOpenProbes requests are let through to decide whether to close.
+type circuitBreaker struct {
+	service  string
+	config   config.BreakerConfig
+	onChange func(BreakerEvent)
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+func newCircuitBreaker(service string, cfg config.BreakerConfig, onChange func(BreakerEvent)) *circuitBreaker {
+	return &circuitBreaker{
+		service:     service,
+		config:      cfg,
+		onChange:    onChange,
+		windowStart: time.Now(),
+	}
+}
+
+// allow reports whether a request may proceed. When it may, done must be
+// called with the outcome; otherwise retryAfter tells how long the breaker
+// stays open.
+func (b *circuitBreaker) allow() (done func(success bool), retryAfter time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case StateOpen:
+		if wait := b.openedAt.Add(b.config.OpenDuration).Sub(now); wait > 0 {
+			return nil, wait, false
+		}
+		b.setState(StateHalfOpen, now)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return nil, b.config.OpenDuration, false
+		}
+		b.probes++
+		return b.onHalfOpenResult, 0, true
+	default:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		return b.onClosedResult, 0, true
+	}
+}
+
+func (b *circuitBreaker) onClosedResult(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		return
+	}
+	b.requests++
+	if !success {
+		b.failures++
+	}
+	if b.requests >= b.config.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.config.FailureRatio {
+		b.setState(StateOpen, time.Now())
+	}
+}
+
+func (b *circuitBreaker) onHalfOpenResult(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateHalfOpen {
+		return
+	}
+	if !success {
+		b.setState(StateOpen, time.Now())
+		return
+	}
+	b.successes++
+	if b.successes >= b.config.HalfOpenProbes {
+		b.setState(StateClosed, time.Now())
+	}
+}
+
+// setState must be called with mu held
+func (b *circuitBreaker) setState(state BreakerState, now time.Time) {
+	from := b.state
+	b.state = state
+	b.probes, b.successes = 0, 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+
+	if b.onChange != nil && from != state {
+		b.onChange(BreakerEvent{Service: b.service, From: from, To: state, Time: now})
+	}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwarding is the client information passed to upstreams
+type forwarding struct {
+	clientIP string
+	proto    string
+	host     string
+	// trusted is set when the direct peer is a trusted proxy whose
+	// forwarding headers may be extended rather than replaced
+	trusted bool
+}
+
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+func (p *ReverseProxy) isTrustedPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range p.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ReverseProxy) forwardingFor(c *gin.Context) forwarding {
+	fwd := forwarding{
+		clientIP: c.ClientIP(),
+		proto:    "http",
+		host:     c.Request.Host,
+		trusted:  p.isTrustedPeer(c.Request.RemoteAddr),
+	}
+	if c.Request.TLS != nil {
+		fwd.proto = "https"
+	}
+	if fwd.trusted {
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+			fwd.proto = proto
+		}
+		if host := c.GetHeader("X-Forwarded-Host"); host != "" {
+			fwd.host = host
+		}
+	}
+	return fwd
+}
+
+// setForwardingHeaders sets X-Forwarded-*, X-Real-IP and Forwarded on the
+// outgoing request. Headers from untrusted peers are replaced; the peer
+// address is appended to X-Forwarded-For by httputil.ReverseProxy itself.
+func setForwardingHeaders(req *http.Request, fwd forwarding) {
+	if !fwd.trusted {
+		req.Header.Del("X-Forwarded-For")
+		req.Header.Del("Forwarded")
+	}
+	req.Header.Set("X-Forwarded-Proto", fwd.proto)
+	req.Header.Set("X-Forwarded-Host", fwd.host)
+	req.Header.Set("X-Real-IP", fwd.clientIP)
+
+	element := "for=" + forwardedNode(peerIP(req.RemoteAddr)) +
+		";host=" + quoteForwarded(fwd.host) +
+		";proto=" + fwd.proto
+	if prior := req.Header.Get("Forwarded"); prior != "" {
+		element = prior + ", " + element
+	}
+	req.Header.Set("Forwarded", element)
+}
+
+func peerIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// forwardedNode formats an address as an RFC 7239 node, quoting IPv6
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return `"[` + ip + `]"`
+	}
+	if ip == "" {
+		return "unknown"
+	}
+	return ip
+}
+
+func quoteForwarded(v string) string {
+	if strings.ContainsAny(v, ":[]\\\" ,;=") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	}
+	return v
+}
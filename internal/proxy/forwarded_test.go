@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+func TestForwardingHeaders(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name    string
+		trusted []string
+		in      map[string]string
+		// {host} stands for the gateway's address
+		want map[string]string
+	}{
+		{
+			name: "untrusted peer headers are replaced",
+			in: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7",
+				"Forwarded":         "for=203.0.113.7",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "evil.example.com",
+			},
+			want: map[string]string{
+				"X-Forwarded-For":   "127.0.0.1",
+				"Forwarded":         `for=127.0.0.1;host="{host}";proto=http`,
+				"X-Forwarded-Proto": "http",
+				"X-Forwarded-Host":  "{host}",
+				"X-Real-IP":         "127.0.0.1",
+			},
+		},
+		{
+			name:    "trusted peer headers are appended",
+			trusted: []string{"127.0.0.1"},
+			in: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7",
+				"Forwarded":         `for="[2001:db8::7]"`,
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com",
+			},
+			want: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7, 127.0.0.1",
+				"Forwarded":         `for="[2001:db8::7]", for=127.0.0.1;host=api.example.com;proto=https`,
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com",
+				"X-Real-IP":         "203.0.113.7",
+			},
+		},
+		{
+			name:    "trusted peer without forwarding headers",
+			trusted: []string{"127.0.0.0/8"},
+			want: map[string]string{
+				"X-Forwarded-For":   "127.0.0.1",
+				"Forwarded":         `for=127.0.0.1;host="{host}";proto=http`,
+				"X-Forwarded-Proto": "http",
+				"X-Forwarded-Host":  "{host}",
+				"X-Real-IP":         "127.0.0.1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(upstream.URL)
+			cfg.Proxy.TrustedProxies = tt.trusted
+			p, err := NewReverseProxy(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The gateway derives client IPs only from trusted peers, as in main
+			engine := gin.New()
+			if err := engine.SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			engine.GET("/*path", p.Handler(config.RouteConfig{Path: "/*path", Service: "svc"}))
+			gw := httptest.NewServer(engine)
+			defer gw.Close()
+			gwURL, _ := url.Parse(gw.URL)
+
+			req, _ := http.NewRequest(http.MethodGet, gw.URL+"/", nil)
+			for k, v := range tt.in {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			for k, v := range tt.want {
+				v = strings.ReplaceAll(v, "{host}", gwURL.Host)
+				if got.Get(k) != v {
+					t.Errorf("%s = %q, want %q", k, got.Get(k), v)
+				}
+			}
+		})
+	}
+}
+
+func TestSetForwardingHeadersIPv6Peer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:51234"
+	setForwardingHeaders(req, forwarding{clientIP: "2001:db8::1", proto: "https", host: "[2001:db8::2]:8443"})
+
+	want := `for="[2001:db8::1]";host="[2001:db8::2]:8443";proto=https`
+	if got := req.Header.Get("Forwarded"); got != want {
+		t.Errorf("Forwarded = %q, want %q", got, want)
+	}
+}
+
+func TestForwardedNode(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1":   "192.0.2.1",
+		"2001:db8::1": `"[2001:db8::1]"`,
+		"":            "unknown",
+	}
+	for ip, want := range tests {
+		if got := forwardedNode(ip); got != want {
+			t.Errorf("forwardedNode(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestQuoteForwarded(t *testing.T) {
+	tests := map[string]string{
+		"api.example.com":      "api.example.com",
+		"api.example.com:8443": `"api.example.com:8443"`,
+		`a"b\c`:                `"a\"b\\c"`,
+	}
+	for v, want := range tests {
+		if got := quoteForwarded(v); got != want {
+			t.Errorf("quoteForwarded(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestIsTrustedPeer(t *testing.T) {
+	p := &ReverseProxy{trustedProxies: parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "::1", "fd00::/8"})}
+	tests := map[string]bool{
+		"10.1.2.3:80":     true,
+		"192.0.2.1:80":    true,
+		"192.0.2.2:80":    false,
+		"[::1]:80":        true,
+		"[fd00::5]:80":    true,
+		"[2001:db8::]:80": false,
+		"203.0.113.7":     false,
+		"not-an-ip":       false,
+	}
+	for addr, want := range tests {
+		if got := p.isTrustedPeer(addr); got != want {
+			t.Errorf("isTrustedPeer(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
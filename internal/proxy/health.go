@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+// upstreamHealth tracks active probe results and passive outlier ejection
+type upstreamHealth struct {
+	mu sync.Mutex
+
+	// Active health checking
+	healthy        bool
+	probeSuccesses int
+	probeFailures  int
+	lastCheck      time.Time
+	lastError      string
+
+	// Passive outlier detection
+	consecutiveFailures int
+	ejections           int
+	ejectedUntil        time.Time
+}
+
+// Available reports whether the upstream passes health checks and is not ejected
+func (u *Upstream) Available() bool {
+	u.health.mu.Lock()
+	defer u.health.mu.Unlock()
+	return u.health.healthy && !time.Now().Before(u.health.ejectedUntil)
+}
+
+// recordProbe applies an active health check result using the thresholds
+func (u *Upstream) recordProbe(err error, cfg config.HealthCheckConfig) {
+	h := &u.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastCheck = time.Now()
+	if err == nil {
+		h.lastError = ""
+		h.probeFailures = 0
+		h.probeSuccesses++
+		if !h.healthy && h.probeSuccesses >= cfg.HealthyThreshold {
+			h.healthy = true
+			slog.Info("Upstream healthy", "upstream", u.URL.String())
+		}
+		return
+	}
+
+	h.lastError = err.Error()
+	h.probeSuccesses = 0
+	h.probeFailures++
+	if h.healthy && h.probeFailures >= cfg.UnhealthyThreshold {
+		h.healthy = false
+		slog.Warn("Upstream unhealthy", "upstream", u.URL.String(), "error", err)
+	}
+}
+
+// recordResult feeds a proxied request outcome into outlier detection.
+// After ConsecutiveFailures errors the upstream is ejected, with the ejection
+// time doubling on each repeated ejection up to MaxEjectionTime.
+func (u *Upstream) recordResult(success bool, cfg config.OutlierConfig) {
+	if !cfg.Enabled {
+		return
+	}
+
+	h := &u.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if success {
+		h.consecutiveFailures = 0
+		return
+	}
+
+	h.consecutiveFailures++
+	now := time.Now()
+	if h.consecutiveFailures < cfg.ConsecutiveFailures || now.Before(h.ejectedUntil) {
+		return
+	}
+
+	// Forget old ejections once the instance has stayed up long enough
+	if !h.ejectedUntil.IsZero() && now.Sub(h.ejectedUntil) > cfg.MaxEjectionTime {
+		h.ejections = 0
+	}
+	h.ejections++
+
+	d := cfg.EjectionTime
+	for i := 1; i < h.ejections && d < cfg.MaxEjectionTime; i++ {
+		d *= 2
+	}
+	if d > cfg.MaxEjectionTime {
+		d = cfg.MaxEjectionTime
+	}
+
+	h.ejectedUntil = now.Add(d)
+	h.consecutiveFailures = 0
+	slog.Warn("Upstream ejected after consecutive failures", "upstream", u.URL.String(), "duration", d.String())
+}
+
+// UpstreamStatus is the admin view of an upstream instance
+type UpstreamStatus struct {
+	URL                 string     `json:"url"`
+	Weight              int        `json:"weight"`
+	Healthy             bool       `json:"healthy"`
+	Ejected             bool       `json:"ejected"`
+	EjectedUntil        *time.Time `json:"ejected_until,omitempty"`
+	Ejections           int        `json:"ejections"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	ActiveRequests      int64      `json:"active_requests"`
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+func (u *Upstream) status() UpstreamStatus {
+	h := &u.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := UpstreamStatus{
+		URL:                 u.URL.String(),
+		Weight:              u.Weight,
+		Healthy:             h.healthy,
+		Ejected:             time.Now().Before(h.ejectedUntil),
+		Ejections:           h.ejections,
+		ConsecutiveFailures: h.consecutiveFailures,
+		ActiveRequests:      u.Active(),
+		LastError:           h.lastError,
+	}
+	if st.Ejected {
+		until := h.ejectedUntil
+		st.EjectedUntil = &until
+	}
+	if !h.lastCheck.IsZero() {
+		last := h.lastCheck
+		st.LastCheck = &last
+	}
+	return st
+}
+
+// StartHealthChecks probes every upstream of services with active health
+// checking enabled until ctx is cancelled
+func (p *ReverseProxy) StartHealthChecks(ctx context.Context) {
+	for _, svc := range p.services {
+		if !svc.config.HealthCheck.Enabled {
+			continue
+		}
+		for _, u := range svc.upstreams {
+			go runHealthCheck(ctx, svc.config.HealthCheck, u)
+		}
+	}
+}
+
+func runHealthCheck(ctx context.Context, cfg config.HealthCheckConfig, u *Upstream) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		u.recordProbe(probe(ctx, client, u, cfg.Path), cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func probe(ctx context.Context, client *http.Client, u *Upstream, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL.JoinPath(path).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "api-gateway-health-check")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CheckService reports whether a service has a usable upstream. With active
+// health checks the tracked state is used; otherwise each upstream's health
+// path is probed until one answers.
+func (p *ReverseProxy) CheckService(ctx context.Context, name string) error {
+	svc, ok := p.services[name]
+	if !ok || len(svc.upstreams) == 0 {
+		return fmt.Errorf("service %s not configured", name)
+	}
+
+	if svc.config.HealthCheck.Enabled {
+		for _, u := range svc.upstreams {
+			if u.Available() {
+				return nil
+			}
+		}
+		return fmt.Errorf("none of %d upstreams is healthy", len(svc.upstreams))
+	}
+
+	var lastErr error
+	for _, u := range svc.upstreams {
+		if lastErr = probe(ctx, http.DefaultClient, u, svc.config.HealthCheck.Path); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no upstream answered health check: %w", lastErr)
+}
+
+// Services returns the configured service names, sorted
+func (p *ReverseProxy) Services() []string {
+	names := make([]string, 0, len(p.services))
+	for key := range p.services {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UpstreamsHandler reports the health state of every upstream instance
+func (p *ReverseProxy) UpstreamsHandler(c *gin.Context) {
+	result := make(map[string][]UpstreamStatus, len(p.services))
+	for key, svc := range p.services {
+		statuses := make([]UpstreamStatus, 0, len(svc.upstreams))
+		for _, u := range svc.upstreams {
+			statuses = append(statuses, u.status())
+		}
+		result[key] = statuses
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"services": result,
+		"time":     time.Now().Unix(),
+	})
+}
@@ -0,0 +1,337 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/apierror"
+	"api-gateway/internal/concurrency"
+	"api-gateway/internal/config"
+	"api-gateway/internal/requestid"
+)
+
+// ReverseProxy handles routing to backend services
+type ReverseProxy struct {
+	config         *config.Config
+	services       map[string]*service
+	trustedProxies []*net.IPNet
+	// inFlight caps proxied requests across all services, nil when disabled
+	inFlight *concurrency.Limiter
+
+	listenersMu      sync.RWMutex
+	breakerListeners []func(BreakerEvent)
+}
+
+// service holds the upstream instances of a backend and how to balance them
+type service struct {
+	name      string
+	config    *config.ServiceConfig
+	upstreams []*Upstream
+	balancer  Balancer
+	breaker   *circuitBreaker
+	budget    *retryBudget
+	proxy     *httputil.ReverseProxy
+	inFlight  *concurrency.Limiter
+}
+
+func NewReverseProxy(cfg *config.Config) (*ReverseProxy, error) {
+	p := &ReverseProxy{
+		config:         cfg,
+		services:       make(map[string]*service, len(cfg.Services)),
+		trustedProxies: parseTrustedProxies(cfg.Proxy.TrustedProxies),
+	}
+	if cfg.Concurrency.Enabled {
+		p.inFlight = concurrency.NewLimiter("global", concurrency.Options{
+			MaxLimit:         cfg.Concurrency.GlobalLimit,
+			LowPriorityShare: cfg.Concurrency.LowPriorityShare,
+		})
+	}
+
+	for key, svcCfg := range cfg.Services {
+		upstreams, err := newUpstreams(svcCfg)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", key, err)
+		}
+		svc := &service{
+			name:      key,
+			config:    svcCfg,
+			upstreams: upstreams,
+			balancer:  newBalancer(svcCfg.LoadBalancer, upstreams),
+		}
+		svc.budget = newRetryBudget(svcCfg.Retry)
+		svc.proxy = &httputil.ReverseProxy{
+			Director:       direct,
+			ModifyResponse: stripGatewayHeaders,
+			Transport:      &serviceTransport{svc: svc, base: newHTTPTransport(svcCfg)},
+			ErrorHandler:   errorHandler(key),
+		}
+		if cfg.Concurrency.Enabled {
+			svc.inFlight = concurrency.NewLimiter(key, concurrency.Options{
+				MaxLimit:         svcCfg.MaxConnections,
+				MinLimit:         cfg.Concurrency.MinLimit,
+				Adaptive:         cfg.Concurrency.Adaptive,
+				LatencyThreshold: cfg.Concurrency.LatencyThreshold,
+				BackoffRatio:     cfg.Concurrency.BackoffRatio,
+				LowPriorityShare: cfg.Concurrency.LowPriorityShare,
+			})
+		}
+		if svcCfg.CircuitBreaker {
+			svc.breaker = newCircuitBreaker(key, svcCfg.Breaker, p.emitBreakerEvent)
+		}
+		p.services[key] = svc
+	}
+
+	return p, nil
+}
+
+// OnBreakerStateChange registers a listener for circuit breaker transitions.
+// Listeners run synchronously on the request path and must not block.
+func (p *ReverseProxy) OnBreakerStateChange(fn func(BreakerEvent)) {
+	p.listenersMu.Lock()
+	defer p.listenersMu.Unlock()
+	p.breakerListeners = append(p.breakerListeners, fn)
+}
+
+func (p *ReverseProxy) emitBreakerEvent(ev BreakerEvent) {
+	slog.Warn("Circuit breaker state changed", "service", ev.Service, "from", ev.From.String(), "to", ev.To.String())
+
+	p.listenersMu.RLock()
+	defer p.listenersMu.RUnlock()
+	for _, fn := range p.breakerListeners {
+		fn(ev)
+	}
+}
+
+// Handler returns a gin handler proxying requests matched by route to its service
+func (p *ReverseProxy) Handler(route config.RouteConfig) gin.HandlerFunc {
+	serviceKey := route.Service
+
+	return func(c *gin.Context) {
+		svc, ok := p.services[serviceKey]
+		if !ok || len(svc.upstreams) == 0 {
+			apierror.AbortService(c, apierror.CodeServiceNotConfigured, "service not configured", serviceKey)
+			return
+		}
+
+		state := &requestState{
+			path:       rewritePath(c, route),
+			forwarding: p.forwardingFor(c),
+			userID:     c.GetString("x_user_id"),
+			username:   c.GetString("x_username"),
+			balanceKey: balanceKey(c),
+		}
+		if svc.config.RetryCount > 0 && isRetryable(c.Request) {
+			body, err := bufferBody(c.Request, svc.config.Retry.BodyLimit)
+			if err != nil {
+				apierror.Abort(c, apierror.CodeBadRequest, "failed to read request body")
+				return
+			}
+			state.body = body
+			state.retryable = body != nil
+		}
+
+		release, ok := p.admit(c, svc)
+		if !ok {
+			c.Header("Retry-After", "1")
+			apierror.AbortService(c, apierror.CodeOverloaded, "too many requests in flight", serviceKey)
+			return
+		}
+
+		var breakerDone func(bool)
+		if svc.breaker != nil {
+			done, retryAfter, ok := svc.breaker.allow()
+			if !ok {
+				release(0, false)
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				apierror.AbortService(c, apierror.CodeCircuitOpen, "circuit breaker open", serviceKey)
+				return
+			}
+			breakerDone = done
+		}
+
+		ctx := c.Request.Context()
+		if svc.config.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, svc.config.Timeout)
+			defer cancel()
+		}
+		c.Request = c.Request.WithContext(context.WithValue(ctx, requestStateKey{}, state))
+
+		c.Set("upstream_service", serviceKey)
+		start := time.Now()
+		panicked := true
+		// Deferred so the slots and the breaker's half-open probe are given
+		// back when the proxy panics with http.ErrAbortHandler, e.g. when the
+		// client disconnects while the response is being copied
+		defer func() {
+			c.Set("upstream", state.upstream)
+
+			// 5xx responses, transport errors (written as 502) and panics
+			// count as failures
+			failed := panicked || c.Writer.Status() >= http.StatusInternalServerError
+			release(time.Since(start), failed)
+			if breakerDone != nil {
+				breakerDone(!failed)
+			}
+		}()
+		svc.proxy.ServeHTTP(c.Writer, c.Request)
+		panicked = false
+	}
+}
+
+// admit applies the global and per-service concurrency limits. Anonymous
+// reads are low priority and shed first when a backend slows down.
+func (p *ReverseProxy) admit(c *gin.Context, svc *service) (release func(time.Duration, bool), ok bool) {
+	priority := concurrency.PriorityNormal
+	if c.GetString("x_user_id") == "" &&
+		(c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) {
+		priority = concurrency.PriorityLow
+	}
+
+	releaseGlobal := func(time.Duration, bool) {}
+	if p.inFlight != nil {
+		if releaseGlobal, ok = p.inFlight.Acquire(priority); !ok {
+			return nil, false
+		}
+	}
+
+	releaseService := func(time.Duration, bool) {}
+	if svc.inFlight != nil {
+		if releaseService, ok = svc.inFlight.Acquire(priority); !ok {
+			releaseGlobal(0, false)
+			return nil, false
+		}
+	}
+
+	return func(latency time.Duration, failed bool) {
+		releaseService(latency, failed)
+		releaseGlobal(latency, failed)
+	}, true
+}
+
+// ConcurrencyStats returns the current in-flight limits, global first
+func (p *ReverseProxy) ConcurrencyStats() []concurrency.Stats {
+	var stats []concurrency.Stats
+	if p.inFlight != nil {
+		stats = append(stats, p.inFlight.Stats())
+	}
+	for _, svc := range p.services {
+		if svc.inFlight != nil {
+			stats = append(stats, svc.inFlight.Stats())
+		}
+	}
+	return stats
+}
+
+// LimitsHandler reports the current concurrency limits
+func (p *ReverseProxy) LimitsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"limits": p.ConcurrencyStats(),
+		"time":   time.Now().Unix(),
+	})
+}
+
+// direct rewrites the outgoing request from the state set up by Handler.
+// The upstream host is chosen per attempt by serviceTransport.
+func direct(req *http.Request) {
+	st := stateFrom(req.Context())
+
+	// st.path is escaped; keep the client's encoding, e.g. %2F, upstream
+	req.URL.Path, _ = url.PathUnescape(st.path)
+	req.URL.RawPath = st.path
+	setForwardingHeaders(req, st.forwarding)
+	if _, exists := req.Header["User-Agent"]; !exists {
+		req.Header["User-Agent"] = []string{"api-gateway"}
+	}
+	if st.userID != "" {
+		req.Header.Set("X-User-ID", st.userID)
+	}
+	if st.username != "" {
+		req.Header.Set("X-Username", st.username)
+	}
+}
+
+// stripGatewayHeaders drops upstream headers the gateway sets itself: CORS
+// headers are owned by the CORS middleware and the request ID middleware
+// already echoed X-Request-ID, so an upstream copy would duplicate it
+func stripGatewayHeaders(resp *http.Response) error {
+	for h := range resp.Header {
+		if strings.HasPrefix(h, "Access-Control-") {
+			resp.Header.Del(h)
+		}
+	}
+	resp.Header.Del(requestid.Header)
+	return nil
+}
+
+// balanceKey returns the key used by hash-based balancing: the authenticated
+// user ID when present, the client IP otherwise
+func balanceKey(c *gin.Context) string {
+	if userID := c.GetString("x_user_id"); userID != "" {
+		return userID
+	}
+	return c.ClientIP()
+}
+
+// rewritePath builds the escaped upstream path from the route's
+// strip/rewrite rules. Rewrite is a template where :name and *name segments
+// are replaced by the matched route params; otherwise StripPrefix is trimmed
+// from the request path. Parts copied from the request keep their original
+// encoding.
+func rewritePath(c *gin.Context, route config.RouteConfig) string {
+	path := c.Request.URL.EscapedPath()
+
+	if route.Rewrite != "" {
+		segs := strings.Split(route.Rewrite, "/")
+		for i, seg := range segs {
+			switch {
+			case strings.HasPrefix(seg, ":"):
+				segs[i] = url.PathEscape(c.Param(seg[1:]))
+			case strings.HasPrefix(seg, "*"):
+				// Catch-all params already carry their leading slash
+				segs[i] = strings.TrimPrefix(escapedSuffix(path, c.Param(seg[1:])), "/")
+			}
+		}
+		return cleanPath(strings.Join(segs, "/"))
+	}
+
+	if route.StripPrefix != "" {
+		prefix := (&url.URL{Path: route.StripPrefix}).EscapedPath()
+		return cleanPath(strings.TrimPrefix(path, prefix))
+	}
+
+	return path
+}
+
+// escapedSuffix returns the tail of the escaped path that decodes to the
+// catch-all value, so an encoded slash inside it stays encoded
+func escapedSuffix(escaped, value string) string {
+	for i := len(escaped) - 1; i >= 0; i-- {
+		if escaped[i] != '/' {
+			continue
+		}
+		if tail, err := url.PathUnescape(escaped[i:]); err == nil && tail == value {
+			return escaped[i:]
+		}
+	}
+	return (&url.URL{Path: value}).EscapedPath()
+}
+
+func cleanPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
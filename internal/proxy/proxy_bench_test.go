@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+)
+
+// benchParallelism keeps more requests in flight than http.DefaultTransport
+// keeps idle connections per host (2)
+const benchParallelism = 8
+
+func benchService(b *testing.B) *config.ServiceConfig {
+	b.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"title":"hello"}`))
+	}))
+	b.Cleanup(upstream.Close)
+
+	// Transport defaults from loadServicesConfig
+	return &config.ServiceConfig{
+		Name:           "svc",
+		URL:            upstream.URL,
+		Upstreams:      []config.UpstreamConfig{{URL: upstream.URL, Weight: 1}},
+		MaxConnections: 100,
+		Transport: config.TransportConfig{
+			MaxIdleConns:        50,
+			IdleConnTimeout:     90 * time.Second,
+			KeepAlive:           30 * time.Second,
+			DialTimeout:         5 * time.Second,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// perRequestHandler is how requests were proxied before services shared a
+// proxy: a ReverseProxy and director built for every request, on
+// http.DefaultTransport
+func perRequestHandler(svc *config.ServiceConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetURL, err := url.Parse(svc.URL)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		fullPath := c.Request.URL.Path
+
+		proxy := httputil.NewSingleHostReverseProxy(targetURL)
+		proxy.Director = func(req *http.Request) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			req.URL.Path = fullPath
+			req.URL.RawQuery = c.Request.URL.RawQuery
+			req.Host = targetURL.Host
+			req.Header = c.Request.Header.Clone()
+			if _, exists := req.Header["User-Agent"]; !exists {
+				req.Header["User-Agent"] = []string{"api-gateway"}
+			}
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// sharedHandler does the same work through one ReverseProxy per service on
+// the pooled transport built by newHTTPTransport
+func sharedHandler(svc *config.ServiceConfig) gin.HandlerFunc {
+	targetURL, _ := url.Parse(svc.URL)
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			req.Host = targetURL.Host
+			if _, exists := req.Header["User-Agent"]; !exists {
+				req.Header["User-Agent"] = []string{"api-gateway"}
+			}
+		},
+		Transport: newHTTPTransport(svc),
+	}
+	return func(c *gin.Context) {
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+func runProxyBenchmark(b *testing.B, handler gin.HandlerFunc) {
+	engine := gin.New()
+	engine.GET("/api/v1/posts/*path", handler)
+	gw := httptest.NewServer(engine)
+	b.Cleanup(gw.Close)
+
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 64}}
+	b.Cleanup(client.CloseIdleConnections)
+	get := func() {
+		req, _ := http.NewRequest(http.MethodGet, gw.URL+"/api/v1/posts/1?fields=title", nil)
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b.Errorf("status = %d", resp.StatusCode)
+		}
+	}
+
+	get()
+
+	b.ReportAllocs()
+	b.SetParallelism(benchParallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			get()
+		}
+	})
+}
+
+func BenchmarkProxyPerRequest(b *testing.B) {
+	runProxyBenchmark(b, perRequestHandler(benchService(b)))
+}
+
+func BenchmarkProxyShared(b *testing.B) {
+	runProxyBenchmark(b, sharedHandler(benchService(b)))
+}
+
+// BenchmarkProxyHandler is the full gateway handler, including forwarding
+// headers, balancing and retry bookkeeping, for reference
+func BenchmarkProxyHandler(b *testing.B) {
+	p, err := NewReverseProxy(&config.Config{
+		Proxy:       &config.ProxyConfig{},
+		Concurrency: &config.ConcurrencyConfig{},
+		Services:    map[string]*config.ServiceConfig{"svc": benchService(b)},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	runProxyBenchmark(b, p.Handler(config.RouteConfig{Path: "/api/v1/posts/*path", Service: "svc"}))
+}
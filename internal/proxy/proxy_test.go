@@ -0,0 +1,367 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/middleware"
+	"api-gateway/internal/requestid"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testConfig returns a config with a single "svc" service pointing at upstream
+func testConfig(upstream string) *config.Config {
+	return &config.Config{
+		Proxy:       &config.ProxyConfig{},
+		Concurrency: &config.ConcurrencyConfig{},
+		Services: map[string]*config.ServiceConfig{
+			"svc": {
+				Name:      "svc",
+				URL:       upstream,
+				Upstreams: []config.UpstreamConfig{{URL: upstream, Weight: 1}},
+			},
+		},
+	}
+}
+
+// newGateway serves route through p behind the gateway's recovery, like main does
+func newGateway(t testing.TB, p *ReverseProxy, route config.RouteConfig) *httptest.Server {
+	t.Helper()
+	engine := gin.New()
+	engine.Use(middleware.Recovery())
+	for _, method := range route.MethodList() {
+		engine.Handle(method, route.Path, p.Handler(route))
+	}
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeTruncated starts a chunked body and drops the connection before the
+// final chunk, which makes the reverse proxy abort the response with
+// http.ErrAbortHandler
+func writeTruncated(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("partial"))
+	w.(http.Flusher).Flush()
+	if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+func truncatedUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTruncated(w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// requireAborted fetches url and fails unless the gateway reset the
+// connection instead of completing the response
+func requireAborted(t *testing.T, url string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("response completed with status %d and body %q, want the connection reset", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerReleasesSlotsWhenResponseAborted(t *testing.T) {
+	upstream := truncatedUpstream(t)
+
+	cfg := testConfig(upstream.URL)
+	cfg.Concurrency = &config.ConcurrencyConfig{Enabled: true, GlobalLimit: 2, LowPriorityShare: 1}
+	cfg.Services["svc"].MaxConnections = 2
+
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"})
+
+	// More requests than the limit: each would leak a slot before the fix,
+	// and a leaked slot would complete the request as a 503
+	for i := 0; i < 5; i++ {
+		requireAborted(t, gw.URL+"/items")
+	}
+
+	for _, st := range p.ConcurrencyStats() {
+		if st.InFlight != 0 {
+			t.Errorf("%s limiter has %d requests in flight after all finished", st.Name, st.InFlight)
+		}
+	}
+}
+
+func TestHandlerFinishesBreakerProbeWhenResponseAborted(t *testing.T) {
+	var mode atomic.Value
+	mode.Store("fail")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch mode.Load() {
+		case "fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "abort":
+			writeTruncated(w)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(upstream.URL)
+	cfg.Services["svc"].CircuitBreaker = true
+	cfg.Services["svc"].Breaker = config.BreakerConfig{
+		FailureRatio:   0.5,
+		MinRequests:    1,
+		Window:         time.Minute,
+		OpenDuration:   20 * time.Millisecond,
+		HalfOpenProbes: 1,
+	}
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"})
+
+	get := func() int {
+		resp, err := http.Get(gw.URL + "/items")
+		if err != nil {
+			return 0
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Open the breaker, then let the half-open probe abort mid-response
+	get()
+	time.Sleep(30 * time.Millisecond)
+	mode.Store("abort")
+	requireAborted(t, gw.URL+"/items")
+
+	// The aborted probe must count as a failure, not hold the probe slot
+	mode.Store("ok")
+	time.Sleep(30 * time.Millisecond)
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("status after recovery = %d, want 200", code)
+	}
+}
+
+func TestHandlerShedsWhenOverloaded(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(upstream.URL)
+	cfg.Concurrency = &config.ConcurrencyConfig{Enabled: true, GlobalLimit: 1, LowPriorityShare: 1}
+	cfg.Services["svc"].MaxConnections = 10
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := http.Get(gw.URL + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	resp, err := http.Get(gw.URL + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	close(unblock)
+	<-done
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if st := p.ConcurrencyStats()[0]; st.ShedLow != 1 {
+		t.Errorf("global limiter shed %d low priority requests, want 1", st.ShedLow)
+	}
+}
+
+func TestHandlerDoesNotDuplicateRequestID(t *testing.T) {
+	received := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(requestid.Header)
+		// Upstreams commonly echo the request ID and set their own CORS headers
+		w.Header().Set(requestid.Header, r.Header.Get(requestid.Header))
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}))
+	t.Cleanup(upstream.Close)
+
+	p, err := NewReverseProxy(testConfig(upstream.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := config.RouteConfig{Path: "/*path", Service: "svc"}
+	engine := gin.New()
+	engine.Use(middleware.RequestID())
+	engine.GET(route.Path, p.Handler(route))
+	gw := httptest.NewServer(engine)
+	t.Cleanup(gw.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, gw.URL+"/items", nil)
+	req.Header.Set(requestid.Header, "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := <-received; got != "abc" {
+		t.Errorf("upstream %s = %q, want abc", requestid.Header, got)
+	}
+	if got := resp.Header.Values(requestid.Header); len(got) != 1 || got[0] != "abc" {
+		t.Errorf("response %s = %q, want [abc]", requestid.Header, got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("upstream CORS header leaked: %q", got)
+	}
+}
+
+func TestHandlerIgnoresClientCancellationForOutliers(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(upstream.URL)
+	cfg.Services["svc"].Outlier = config.OutlierConfig{
+		Enabled:             true,
+		ConsecutiveFailures: 1,
+		EjectionTime:        time.Minute,
+		MaxEjectionTime:     time.Minute,
+	}
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-entered
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, gw.URL+"/items", nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("request completed, want it canceled")
+	}
+
+	// Give the gateway time to observe the cancellation
+	time.Sleep(50 * time.Millisecond)
+	if st := p.services["svc"].upstreams[0].status(); st.Ejected || st.ConsecutiveFailures != 0 {
+		t.Fatalf("upstream status = %+v, want client cancellation not counted", st)
+	}
+}
+
+func TestHandlerEjectsHangingUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(upstream.URL)
+	cfg.Services["svc"].Timeout = 20 * time.Millisecond
+	cfg.Services["svc"].Outlier = config.OutlierConfig{
+		Enabled:             true,
+		ConsecutiveFailures: 1,
+		EjectionTime:        time.Minute,
+		MaxEjectionTime:     time.Minute,
+	}
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"})
+
+	resp, err := http.Get(gw.URL + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", resp.StatusCode)
+	}
+
+	// The service timeout is the upstream's fault, unlike a client cancellation
+	if st := p.services["svc"].upstreams[0].status(); !st.Ejected {
+		t.Fatalf("upstream status = %+v, want the hanging instance ejected", st)
+	}
+}
+
+func TestHandlerUpstreamPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		route config.RouteConfig
+		path  string
+		want  string
+	}{
+		{"unchanged", config.RouteConfig{Path: "/api/v1/users/*path"}, "/api/v1/users/42", "/api/v1/users/42"},
+		{"encoded slash kept", config.RouteConfig{Path: "/api/v1/users/*path"}, "/api/v1/users/john%2Fdoe", "/api/v1/users/john%2Fdoe"},
+		{"encoded space kept", config.RouteConfig{Path: "/api/v1/users/*path"}, "/api/v1/users/john%20doe", "/api/v1/users/john%20doe"},
+		{"strip prefix", config.RouteConfig{Path: "/api/v1/users/*path", StripPrefix: "/api/v1"}, "/api/v1/users/john%2Fdoe", "/users/john%2Fdoe"},
+		{"rewrite catch-all", config.RouteConfig{Path: "/api/v1/users/*path", Rewrite: "/v2/accounts/*path"}, "/api/v1/users/john%2Fdoe/posts", "/v2/accounts/john%2Fdoe/posts"},
+		{"rewrite param", config.RouteConfig{Path: "/api/v1/posts/:id", Rewrite: "/posts/:id/view"}, "/api/v1/posts/a%3Fb", "/posts/a%3Fb/view"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan string, 1)
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received <- r.RequestURI
+			}))
+			t.Cleanup(upstream.Close)
+
+			p, err := NewReverseProxy(testConfig(upstream.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.route.Service = "svc"
+			gw := newGateway(t, p, tt.route)
+
+			resp, err := http.Get(gw.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := <-received; got != tt.want {
+				t.Errorf("upstream path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"api-gateway/internal/config"
+)
+
+const retryBudgetWindow = 10 * time.Second
+
+// retryBudget caps retries to a ratio of the requests seen in the current
+// window, with a floor of BudgetMin retries, so a struggling backend is not
+// hit with a retry storm
+type retryBudget struct {
+	ratio float64
+	min   int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+func newRetryBudget(cfg config.RetryConfig) *retryBudget {
+	return &retryBudget{
+		ratio:       cfg.BudgetRatio,
+		min:         cfg.BudgetMin,
+		windowStart: time.Now(),
+	}
+}
+
+func (b *retryBudget) roll(now time.Time) {
+	if now.Sub(b.windowStart) >= retryBudgetWindow {
+		b.windowStart, b.requests, b.retries = now, 0, 0
+	}
+}
+
+// request records an original (non-retry) request
+func (b *retryBudget) request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(time.Now())
+	b.requests++
+}
+
+// withdraw reports whether one more retry fits in the budget
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(time.Now())
+
+	allowed := int(float64(b.requests) * b.ratio)
+	if allowed < b.min {
+		allowed = b.min
+	}
+	if b.retries >= allowed {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// backoff returns the wait before the given retry attempt (1-based) using
+// exponential backoff with full jitter
+func backoff(cfg config.RetryConfig, attempt int) time.Duration {
+	d := cfg.Backoff
+	for i := 1; i < attempt && d < cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > cfg.MaxBackoff {
+		d = cfg.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// isRetryable reports whether a request may be replayed safely
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// isRetryableStatus reports whether an upstream response is worth retrying
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// bufferBody reads up to limit bytes of the request body so it can be
+// replayed. If the body is larger it is left streaming and nil is returned.
+func bufferBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return []byte{}, nil
+	}
+	if req.ContentLength > limit {
+		return nil, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > limit {
+		// Too large to replay, stitch the consumed prefix back on
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return nil, nil
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return buf, nil
+}
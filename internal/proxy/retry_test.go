@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"api-gateway/internal/config"
+)
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(config.RetryConfig{BudgetRatio: 0.2, BudgetMin: 1})
+
+	// The floor allows one retry before any traffic
+	if !b.withdraw() || b.withdraw() {
+		t.Fatal("want exactly BudgetMin retries without traffic")
+	}
+
+	// 20% of 10 requests: one more retry on top of the one already used
+	for i := 0; i < 10; i++ {
+		b.request()
+	}
+	if !b.withdraw() || b.withdraw() {
+		t.Fatal("want two retries in total for 10 requests at ratio 0.2")
+	}
+
+	// A new window resets the budget
+	b.mu.Lock()
+	b.windowStart = time.Now().Add(-retryBudgetWindow)
+	b.mu.Unlock()
+	if !b.withdraw() {
+		t.Fatal("budget not refilled in a new window")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.RetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: 400 * time.Millisecond}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if d := backoff(cfg, tt.attempt); d < 0 || d >= tt.max {
+				t.Fatalf("backoff(attempt %d) = %v, want in [0, %v)", tt.attempt, d, tt.max)
+			}
+		}
+	}
+	if d := backoff(config.RetryConfig{}, 3); d != 0 {
+		t.Errorf("backoff without a base = %v, want 0", d)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		method string
+		key    string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodHead, "", true},
+		{http.MethodOptions, "", true},
+		{http.MethodPut, "", true},
+		{http.MethodDelete, "", true},
+		{http.MethodPost, "", false},
+		{http.MethodPatch, "", false},
+		{http.MethodPost, "abc", true},
+		{http.MethodPatch, "abc", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.key != "" {
+			req.Header.Set("Idempotency-Key", tt.key)
+		}
+		if got := isRetryable(req); got != tt.want {
+			t.Errorf("isRetryable(%s, key %q) = %v, want %v", tt.method, tt.key, got, tt.want)
+		}
+	}
+
+	for code, want := range map[int]bool{
+		http.StatusOK: false, http.StatusInternalServerError: false, http.StatusBadGateway: true,
+		http.StatusServiceUnavailable: true, http.StatusGatewayTimeout: true,
+	} {
+		if got := isRetryableStatus(code); got != want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestBufferBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		unsized    bool
+		wantBuffer bool
+	}{
+		{"no body", "", false, true},
+		{"within limit", "hello", false, true},
+		{"exactly the limit", "0123456789", false, true},
+		{"content length over limit", "01234567890123", false, false},
+		// Read past the limit, then stitched back together
+		{"unknown length over limit", "01234567890123", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			if tt.body == "" {
+				req.Body = http.NoBody
+			}
+			if tt.unsized {
+				req.ContentLength = -1
+			}
+
+			buf, err := bufferBody(req, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (buf != nil) != tt.wantBuffer {
+				t.Fatalf("buffered = %v, want %v", buf != nil, tt.wantBuffer)
+			}
+			if buf != nil && string(buf) != tt.body {
+				t.Errorf("buffer = %q, want %q", buf, tt.body)
+			}
+
+			// The body forwarded upstream is always complete
+			got, _ := io.ReadAll(req.Body)
+			if string(got) != tt.body {
+				t.Errorf("body after buffering = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+// retryUpstream answers with the given statuses in turn, then 200, and
+// records the body of every attempt
+type retryUpstream struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (u *retryUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	status := http.StatusOK
+	if n := len(u.bodies); n < len(u.statuses) {
+		status = u.statuses[n]
+	}
+	u.bodies = append(u.bodies, string(body))
+	w.WriteHeader(status)
+}
+
+func (u *retryUpstream) attempts() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.bodies...)
+}
+
+func newRetryGateway(t *testing.T, upstream http.Handler, retry config.RetryConfig) string {
+	t.Helper()
+	srv := httptest.NewServer(upstream)
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(srv.URL)
+	cfg.Services["svc"].RetryCount = 2
+	cfg.Services["svc"].Retry = retry
+	p, err := NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newGateway(t, p, config.RouteConfig{Path: "/*path", Service: "svc"}).URL
+}
+
+func send(t *testing.T, method, url, body, idempotencyKey string) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHandlerRetries(t *testing.T) {
+	retry := config.RetryConfig{BodyLimit: 1024, BudgetMin: 10}
+	tests := []struct {
+		name         string
+		method       string
+		key          string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{"PUT is replayed with the same body", http.MethodPut, "", http.StatusOK, 3},
+		{"POST without a key is not retried", http.MethodPost, "", http.StatusServiceUnavailable, 1},
+		{"POST with an idempotency key is retried", http.MethodPost, "k1", http.StatusOK, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &retryUpstream{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+			gw := newRetryGateway(t, upstream, retry)
+
+			if code := send(t, tt.method, gw+"/items", `{"title":"hello"}`, tt.key); code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", code, tt.wantStatus)
+			}
+			attempts := upstream.attempts()
+			if len(attempts) != tt.wantAttempts {
+				t.Fatalf("upstream saw %d attempts, want %d", len(attempts), tt.wantAttempts)
+			}
+			for i, body := range attempts {
+				if body != `{"title":"hello"}` {
+					t.Errorf("attempt %d body = %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerRetriesStopWhenBudgetIsEmpty(t *testing.T) {
+	upstream := &retryUpstream{statuses: []int{503, 503, 503, 503, 503}}
+	gw := newRetryGateway(t, upstream, config.RetryConfig{BodyLimit: 1024, BudgetMin: 1})
+
+	// The first request spends the only retry, the second gets none
+	send(t, http.MethodGet, gw+"/items", "", "")
+	if n := len(upstream.attempts()); n != 2 {
+		t.Fatalf("first request made %d attempts, want 2", n)
+	}
+	send(t, http.MethodGet, gw+"/items", "", "")
+	if n := len(upstream.attempts()); n != 3 {
+		t.Fatalf("second request made %d attempts in total, want 3", n)
+	}
+}
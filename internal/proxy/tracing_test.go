@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/tracing"
+)
+
+func TestHandlerPropagatesTraceContext(t *testing.T) {
+	if _, err := tracing.Init(context.Background(), &config.Config{Metrics: &config.MetricsConfig{}}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	traceparent := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("traceparent")
+	}))
+	t.Cleanup(upstream.Close)
+
+	p, err := NewReverseProxy(testConfig(upstream.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := config.RouteConfig{Path: "/*path", Service: "svc"}
+	engine := gin.New()
+	engine.Use(tracing.Middleware())
+	engine.GET(route.Path, p.Handler(route))
+	gw := httptest.NewServer(engine)
+	t.Cleanup(gw.Close)
+
+	resp, err := http.Get(gw.URL + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The server span ends after the response has been written
+	var spans []sdktrace.ReadOnlySpan
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if spans = recorder.Ended(); len(spans) == 2 {
+			break
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want server and client", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if client.SpanKind() != trace.SpanKindClient || server.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("span kinds = %v, %v", client.SpanKind(), server.SpanKind())
+	}
+	if client.Name() != "GET svc" {
+		t.Errorf("client span name = %q, want %q", client.Name(), "GET svc")
+	}
+	if client.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("client span is not a child of the server span")
+	}
+
+	// The upstream continues the trace from the client span
+	want := "00-" + client.SpanContext().TraceID().String() + "-" + client.SpanContext().SpanID().String() + "-01"
+	if got := <-traceparent; got != want {
+		t.Errorf("upstream traceparent = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"api-gateway/internal/apierror"
+	"api-gateway/internal/config"
+	"api-gateway/internal/metrics"
+	"api-gateway/internal/tracing"
+)
+
+var errNoUpstream = errors.New("no upstream available")
+
+type requestStateKey struct{}
+
+// requestState carries per-request data from the handler to the shared
+// director and transport of a service
+type requestState struct {
+	path       string
+	forwarding forwarding
+	userID     string
+	username   string
+	balanceKey string
+	// upstream is the host of the last attempted upstream instance
+	upstream string
+	// body is the buffered request body, nil when it cannot be replayed
+	body      []byte
+	retryable bool
+}
+
+func stateFrom(ctx context.Context) *requestState {
+	if st, ok := ctx.Value(requestStateKey{}).(*requestState); ok {
+		return st
+	}
+	return &requestState{}
+}
+
+// newHTTPTransport builds the pooled transport used for every upstream of a service
+func newHTTPTransport(svc *config.ServiceConfig) *http.Transport {
+	cfg := svc.Transport
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxConnsPerHost:       svc.MaxConnections,
+		MaxIdleConns:          cfg.MaxIdleConns * len(svc.Upstreams),
+		MaxIdleConnsPerHost:   cfg.MaxIdleConns,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// serviceTransport picks an upstream for every attempt and retries failed
+// attempts within the service's retry policy and budget
+type serviceTransport struct {
+	svc  *service
+	base http.RoundTripper
+}
+
+func (t *serviceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	st := stateFrom(req.Context())
+	cfg := t.svc.config
+
+	attempts := 1
+	if st.retryable {
+		attempts += cfg.RetryCount
+	}
+	t.svc.budget.request()
+
+	var prev *Upstream
+	for attempt := 1; ; attempt++ {
+		upstream := t.svc.pick(st.balanceKey, prev)
+		if upstream == nil {
+			return nil, errNoUpstream
+		}
+
+		outreq := req
+		if attempt > 1 {
+			outreq = req.Clone(req.Context())
+			outreq.Body = io.NopCloser(bytes.NewReader(st.body))
+		}
+		outreq.URL.Scheme = upstream.URL.Scheme
+		outreq.URL.Host = upstream.URL.Host
+		outreq.Host = upstream.URL.Host
+
+		st.upstream = upstream.URL.Host
+		slog.DebugContext(req.Context(), "Proxying request",
+			"method", req.Method, "path", req.URL.Path, "upstream", upstream.URL.String(),
+			"attempt", attempt, "attempts", attempts)
+
+		ctx, span := t.startSpan(outreq, attempt)
+		outreq = outreq.WithContext(ctx)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(outreq.Header))
+
+		upstream.acquire()
+		resp, err := t.base.RoundTrip(outreq)
+		endSpan(span, resp, err)
+		if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+			// Upgraded connections need the raw body, don't wrap it
+			upstream.release()
+		} else {
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: upstream.release}
+		}
+
+		// A client going away says nothing about the upstream's health, but
+		// hitting the service timeout does
+		if !errors.Is(req.Context().Err(), context.Canceled) {
+			upstream.recordResult(err == nil && resp.StatusCode < http.StatusInternalServerError, cfg.Outlier)
+		}
+
+		failed := err != nil || isRetryableStatus(resp.StatusCode)
+		if !failed || attempt >= attempts || req.Context().Err() != nil || !t.svc.budget.withdraw() {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		wait := time.NewTimer(backoff(cfg.Retry, attempt))
+		select {
+		case <-req.Context().Done():
+			wait.Stop()
+			return nil, req.Context().Err()
+		case <-wait.C:
+		}
+		prev = upstream
+	}
+}
+
+// startSpan starts a client span for one upstream attempt. Retries are
+// sibling spans under the gateway's server span.
+func (t *serviceTransport) startSpan(req *http.Request, attempt int) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(req.Context(), req.Method+" "+t.svc.name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLPath(req.URL.Path),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.HTTPRequestResendCount(attempt-1),
+			semconv.PeerService(t.svc.name),
+		),
+	)
+}
+
+func endSpan(span trace.Span, resp *http.Response, err error) {
+	defer span.End()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+}
+
+// statusClientClosedRequest is logged when the client went away before the
+// upstream answered (nginx convention)
+const statusClientClosedRequest = 499
+
+// errorHandler maps transport errors to the gateway error envelope
+func errorHandler(serviceKey string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		slog.WarnContext(r.Context(), "Proxy error",
+			"method", r.Method, "path", r.URL.Path, "service", serviceKey, "error", err)
+
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled) && r.Context().Err() != nil:
+			metrics.UpstreamErrors.WithLabelValues(serviceKey, "canceled").Inc()
+			w.WriteHeader(statusClientClosedRequest)
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			metrics.UpstreamErrors.WithLabelValues(serviceKey, "timeout").Inc()
+			apierror.Write(w, r, apierror.CodeUpstreamTimeout, "upstream request timed out", serviceKey)
+		case errors.Is(err, syscall.ECONNREFUSED):
+			metrics.UpstreamErrors.WithLabelValues(serviceKey, "connection_refused").Inc()
+			apierror.Write(w, r, apierror.CodeConnectionRefused, "upstream refused the connection", serviceKey)
+		case errors.Is(err, errNoUpstream):
+			metrics.UpstreamErrors.WithLabelValues(serviceKey, "no_upstream").Inc()
+			apierror.Write(w, r, apierror.CodeNoUpstream, "no upstream available", serviceKey)
+		default:
+			metrics.UpstreamErrors.WithLabelValues(serviceKey, "other").Inc()
+			apierror.Write(w, r, apierror.CodeBadGateway, "upstream request failed", serviceKey)
+		}
+	}
+}
+
+// pick selects an upstream, trying to avoid the one that just failed
+func (s *service) pick(key string, prev *Upstream) *Upstream {
+	u := s.balancer.Next(key)
+	if u != nil && u == prev && len(s.upstreams) > 1 {
+		u = s.balancer.Next(key)
+	}
+	return u
+}
+
+// releaseOnClose keeps an upstream's in-flight count until the response
+// body has been fully copied to the client
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
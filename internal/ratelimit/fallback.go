@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// FallbackStore uses the primary store and switches to the fallback while
+// the primary is failing. The primary is retried after cooldown.
+type FallbackStore struct {
+	primary  Store
+	fallback Store
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func NewFallbackStore(primary, fallback Store, cooldown time.Duration) *FallbackStore {
+	return &FallbackStore{primary: primary, fallback: fallback, cooldown: cooldown}
+}
+
+func (s *FallbackStore) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if s.primaryDown() {
+		return s.fallback.Allow(ctx, key, rule)
+	}
+
+	res, err := s.primary.Allow(ctx, key, rule)
+	if err == nil {
+		return res, nil
+	}
+	// A client that went away or a request that timed out cancels the
+	// call; that says nothing about the primary
+	if ctx.Err() != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	if time.Now().After(s.downUntil) {
+		slog.Warn("Rate limit store unavailable, using local limits", "cooldown", s.cooldown.String(), "error", err)
+	}
+	s.downUntil = time.Now().Add(s.cooldown)
+	s.mu.Unlock()
+
+	return s.fallback.Allow(ctx, key, rule)
+}
+
+func (s *FallbackStore) primaryDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.downUntil)
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingStore records how many checks reached the wrapped store
+type countingStore struct {
+	Store
+	calls int
+}
+
+func (s *countingStore) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	s.calls++
+	return s.Store.Allow(ctx, key, rule)
+}
+
+func TestFallbackStoreFailover(t *testing.T) {
+	m, redisStore, _ := testRedis(t)
+	primary := &countingStore{Store: redisStore}
+	fallback := &countingStore{Store: NewMemoryStore()}
+	store := NewFallbackStore(primary, fallback, 50*time.Millisecond)
+
+	rule := Rule{Strategy: StrategyFixedWindow, Limit: 100, Window: time.Minute}
+	ctx := context.Background()
+	check := func(wantPrimary, wantFallback int) {
+		t.Helper()
+		if _, err := store.Allow(ctx, "client", rule); err != nil {
+			t.Fatal(err)
+		}
+		if primary.calls != wantPrimary || fallback.calls != wantFallback {
+			t.Fatalf("calls primary/fallback = %d/%d, want %d/%d",
+				primary.calls, fallback.calls, wantPrimary, wantFallback)
+		}
+	}
+
+	check(1, 0)
+
+	// Redis fails: the failing call and the cooldown are served locally
+	m.SetError("LOADING Redis is loading the dataset in memory")
+	check(2, 1)
+	m.SetError("")
+	check(2, 2)
+
+	// After the cooldown the primary is tried again
+	time.Sleep(60 * time.Millisecond)
+	check(3, 2)
+}
+
+func TestFallbackStoreIgnoresCanceledRequests(t *testing.T) {
+	_, redisStore, _ := testRedis(t)
+	primary := &countingStore{Store: redisStore}
+	fallback := &countingStore{Store: NewMemoryStore()}
+	store := NewFallbackStore(primary, fallback, time.Minute)
+	rule := Rule{Strategy: StrategyFixedWindow, Limit: 100, Window: time.Minute}
+
+	// A client that disconnects must not switch the replica to local limits
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Allow(ctx, "client", rule); err == nil {
+		t.Fatal("expected the canceled check to fail")
+	}
+
+	if _, err := store.Allow(context.Background(), "client", rule); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != 2 || fallback.calls != 0 {
+		t.Fatalf("calls primary/fallback = %d/%d, want 2/0", primary.calls, fallback.calls)
+	}
+}
@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store. Limits are enforced per gateway replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// entry holds the state of one key; only the fields of its strategy are used
+type entry struct {
+	lastSeen time.Time
+	window   time.Duration
+
+	// token-bucket
+	tokens     float64
+	lastRefill time.Time
+
+	// fixed-window
+	windowStart time.Time
+	count       int
+
+	// sliding-window
+	log []time.Time
+
+	// gcra: theoretical arrival time of the next request
+	tat time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, rule Rule) (Result, error) {
+	if err := rule.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, rule.Window)
+
+	key = rule.Strategy + ":" + key
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{tokens: float64(rule.Limit), lastRefill: now, windowStart: now}
+		s.entries[key] = e
+	}
+	e.lastSeen = now
+	e.window = rule.Window
+
+	switch rule.Strategy {
+	case StrategyFixedWindow:
+		return e.fixedWindow(now, rule), nil
+	case StrategySlidingWindow:
+		return e.slidingWindow(now, rule), nil
+	case StrategyGCRA:
+		return e.gcra(now, rule), nil
+	default:
+		return e.tokenBucket(now, rule), nil
+	}
+}
+
+// tokenBucket holds up to Limit tokens refilled at Limit per Window
+func (e *entry) tokenBucket(now time.Time, rule Rule) Result {
+	rate := float64(rule.Limit) / rule.Window.Seconds()
+	e.tokens = math.Min(float64(rule.Limit), e.tokens+now.Sub(e.lastRefill).Seconds()*rate)
+	e.lastRefill = now
+
+	res := Result{Limit: rule.Limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - e.tokens) / rate)
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = secondsToDuration((float64(rule.Limit) - e.tokens) / rate)
+	return res
+}
+
+// fixedWindow counts requests in a window starting at the first request,
+// like the Redis script, so limits carry over when FallbackStore switches
+func (e *entry) fixedWindow(now time.Time, rule Rule) Result {
+	if !now.Before(e.windowStart.Add(rule.Window)) {
+		e.windowStart, e.count = now, 0
+	}
+
+	res := Result{Limit: rule.Limit, Reset: e.windowStart.Add(rule.Window).Sub(now)}
+	if e.count < rule.Limit {
+		e.count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = res.Reset
+	}
+	res.Remaining = rule.Limit - e.count
+	return res
+}
+
+// slidingWindow keeps a log of request times within the last Window
+func (e *entry) slidingWindow(now time.Time, rule Rule) Result {
+	cutoff := now.Add(-rule.Window)
+	i := 0
+	for i < len(e.log) && !e.log[i].After(cutoff) {
+		i++
+	}
+	e.log = e.log[i:]
+
+	res := Result{Limit: rule.Limit}
+	if len(e.log) < rule.Limit {
+		e.log = append(e.log, now)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = e.log[0].Add(rule.Window).Sub(now)
+	}
+	res.Remaining = rule.Limit - len(e.log)
+	res.Reset = e.log[len(e.log)-1].Add(rule.Window).Sub(now)
+	return res
+}
+
+// gcra is the generic cell rate algorithm: one request is allowed every
+// Window/Limit with a burst of up to Limit requests
+func (e *entry) gcra(now time.Time, rule Rule) Result {
+	emission := rule.Window / time.Duration(rule.Limit)
+	tolerance := emission * time.Duration(rule.Limit)
+
+	tat := e.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(emission)
+	allowAt := newTAT.Add(-tolerance)
+
+	res := Result{Limit: rule.Limit}
+	if now.Before(allowAt) {
+		res.RetryAfter = allowAt.Sub(now)
+		res.Reset = tat.Sub(now)
+		return res
+	}
+
+	e.tat = newTAT
+	res.Allowed = true
+	res.Remaining = int((tolerance - newTAT.Sub(now)) / emission)
+	res.Reset = newTAT.Sub(now)
+	return res
+}
+
+// sweep drops keys idle for longer than their window, at most once per window
+func (s *MemoryStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if now.Sub(e.lastSeen) > e.window {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
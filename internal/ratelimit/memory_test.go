@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testMemory returns a MemoryStore on a controllable clock. The clock does
+// not start on a second boundary, so windows aligned to the clock instead
+// of the first request would show.
+func testMemory() (*MemoryStore, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 250*int(time.Millisecond), time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.lastSweep = now
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStoreFixedWindow(t *testing.T) {
+	store, advance := testMemory()
+	runSteps(t, store, advance, fixedWindowRule, fixedWindowSteps)
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	store, advance := testMemory()
+	runSteps(t, store, advance, slidingWindowRule, slidingWindowSteps)
+}
+
+func TestMemoryStoreGCRA(t *testing.T) {
+	store, advance := testMemory()
+	runSteps(t, store, advance, gcraRule(StrategyGCRA), gcraSteps)
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store, advance := testMemory()
+	runSteps(t, store, advance, gcraRule(StrategyTokenBucket), gcraSteps)
+
+	// Tokens refill continuously, capped at the limit
+	store, advance = testMemory()
+	rule := Rule{Strategy: StrategyTokenBucket, Limit: 4, Window: time.Second}
+	runSteps(t, store, advance, rule, []step{
+		{0, true, 3, 0},
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 250 * time.Millisecond},
+		{500 * time.Millisecond, true, 1, 0},
+		{time.Hour, true, 3, 0},
+	})
+}
+
+func TestMemoryStoreKeysAndStrategiesAreIndependent(t *testing.T) {
+	store, _ := testMemory()
+	ctx := context.Background()
+	fixed := Rule{Strategy: StrategyFixedWindow, Limit: 1, Window: time.Minute}
+	sliding := Rule{Strategy: StrategySlidingWindow, Limit: 1, Window: time.Minute}
+
+	for _, c := range []struct {
+		key  string
+		rule Rule
+		want bool
+	}{
+		{"a", fixed, true},
+		{"a", fixed, false},
+		{"b", fixed, true},
+		{"a", sliding, true},
+	} {
+		if res, _ := store.Allow(ctx, c.key, c.rule); res.Allowed != c.want {
+			t.Fatalf("%s %s: allowed = %v, want %v", c.rule.Strategy, c.key, res.Allowed, c.want)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsIdleKeys(t *testing.T) {
+	store, advance := testMemory()
+	rule := Rule{Strategy: StrategyFixedWindow, Limit: 1, Window: time.Second}
+	ctx := context.Background()
+
+	store.Allow(ctx, "a", rule)
+	advance(2 * time.Second)
+	store.Allow(ctx, "b", rule)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.entries[StrategyFixedWindow+":a"]; ok {
+		t.Fatal("idle key was not swept")
+	}
+	if len(store.entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(store.entries))
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/apierror"
+	"api-gateway/internal/config"
+)
+
+// Middleware rejects requests over their policy's limit with 429. The first
+// matching policy applies; clients are keyed by the user ID set by the JWT
+// middleware, falling back to the client IP.
+type Middleware struct {
+	store    Store
+	policies []*Policy
+}
+
+func NewMiddleware(store Store, cfg *config.RateLimitConfig) (*Middleware, error) {
+	policies, err := newPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Middleware{store: store, policies: policies}, nil
+}
+
+func (m *Middleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := m.match(c)
+		key := policy.Name + ":" + clientKey(c)
+
+		res, err := m.store.Allow(c.Request.Context(), key, policy.Rule)
+		if err != nil {
+			// Fail open, an unavailable limiter must not take the gateway down
+			slog.ErrorContext(c.Request.Context(), "Rate limiter error", "error", err)
+			c.Next()
+			return
+		}
+
+		if policy.DryRun {
+			if !res.Allowed {
+				slog.InfoContext(c.Request.Context(), "Rate limit dry run: would reject",
+					"method", c.Request.Method, "path", c.Request.URL.Path, "client", clientKey(c), "policy", policy.Name)
+			}
+			c.Next()
+			return
+		}
+
+		setHeaders(c, res)
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			apierror.Abort(c, apierror.CodeRateLimited, "rate limit exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (m *Middleware) match(c *gin.Context) *Policy {
+	for _, p := range m.policies {
+		if p.matches(c) {
+			return p
+		}
+	}
+	// Unreachable, the default policy matches everything
+	return m.policies[len(m.policies)-1]
+}
+
+func clientKey(c *gin.Context) string {
+	if userID := c.GetString("x_user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func setHeaders(c *gin.Context, res Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
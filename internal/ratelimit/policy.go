@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"api-gateway/internal/config"
+)
+
+// Policy is a rate limit rule applied to requests matching its route
+// patterns, methods and JWT roles/claims. Empty matchers match everything.
+// Roles and claims are set by the JWT middleware, which only runs on routes
+// with auth: true, so role or claim policies never match public routes.
+type Policy struct {
+	Name     string
+	Rule     Rule
+	Routes   []string
+	Methods  []string
+	Roles    []string
+	Claims   map[string]string
+	Priority int
+	DryRun   bool
+}
+
+// specificity ranks how narrowly a policy targets requests: a route match
+// outweighs a method match, which outweighs a role or claim match
+func (p *Policy) specificity() int {
+	score := 0
+	if len(p.Routes) > 0 {
+		score += 4
+	}
+	if len(p.Methods) > 0 {
+		score += 2
+	}
+	if len(p.Roles) > 0 || len(p.Claims) > 0 {
+		score++
+	}
+	return score
+}
+
+func (p *Policy) matches(c *gin.Context) bool {
+	if len(p.Routes) > 0 {
+		matched := false
+		for _, route := range p.Routes {
+			if config.MatchPath(route, c.Request.URL.Path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Methods) > 0 {
+		matched := false
+		for _, m := range p.Methods {
+			if strings.EqualFold(m, c.Request.Method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Roles) > 0 {
+		roles, _ := c.Get("x_user_roles")
+		userRoles, _ := roles.([]string)
+		matched := false
+		for _, want := range p.Roles {
+			for _, have := range userRoles {
+				if want == have {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Claims) > 0 {
+		value, _ := c.Get("jwt_claims")
+		claims, ok := value.(jwt.MapClaims)
+		if !ok {
+			return false
+		}
+		for name, want := range p.Claims {
+			if fmt.Sprint(claims[name]) != want {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// newPolicies converts configured policies, filling unset fields from the
+// global limit, and orders them by precedence: higher Priority first, then
+// more specific matchers, then configuration order. The global limit is
+// appended as the catch-all "default" policy.
+func newPolicies(cfg *config.RateLimitConfig) ([]*Policy, error) {
+	global := Rule{Strategy: cfg.Strategy, Limit: cfg.Requests, Window: cfg.Window}
+
+	policies := make([]*Policy, 0, len(cfg.Policies)+1)
+	for _, pc := range cfg.Policies {
+		rule := global
+		if pc.Strategy != "" {
+			rule.Strategy = pc.Strategy
+		}
+		if pc.Requests > 0 {
+			rule.Limit = pc.Requests
+		}
+		if pc.Window > 0 {
+			rule.Window = time.Duration(pc.Window)
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rate limit policy %s: %w", pc.Name, err)
+		}
+
+		policies = append(policies, &Policy{
+			Name:     pc.Name,
+			Rule:     rule,
+			Routes:   pc.Routes,
+			Methods:  pc.Methods,
+			Roles:    pc.Roles,
+			Claims:   pc.Claims,
+			Priority: pc.Priority,
+			DryRun:   pc.DryRun || cfg.DryRun,
+		})
+	}
+
+	sort.SliceStable(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].specificity() > policies[j].specificity()
+	})
+
+	return append(policies, &Policy{Name: "default", Rule: global, DryRun: cfg.DryRun}), nil
+}
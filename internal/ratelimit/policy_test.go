@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"api-gateway/internal/config"
+)
+
+func TestPolicyPrecedence(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, err := NewMiddleware(NewMemoryStore(), &config.RateLimitConfig{
+		Requests: 100,
+		Window:   time.Minute,
+		Strategy: StrategyFixedWindow,
+		Policies: []config.RateLimitPolicy{
+			{Name: "premium", Claims: map[string]string{"plan": "premium"}},
+			{Name: "writes", Methods: []string{"POST"}},
+			{Name: "posts", Routes: []string{"/api/v1/posts/*path"}},
+			{Name: "posts-copy", Routes: []string{"/api/v1/posts/*path"}},
+			{Name: "post-writes", Routes: []string{"/api/v1/posts/*path"}, Methods: []string{"post"}},
+			{Name: "admins", Roles: []string{"admin"}, Priority: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		roles  []string
+		claims jwt.MapClaims
+		want   string
+	}{
+		{"priority beats specificity", http.MethodPost, "/api/v1/posts/1", []string{"user", "admin"}, nil, "admins"},
+		{"route and method beat route", http.MethodPost, "/api/v1/posts/1", nil, nil, "post-writes"},
+		{"equal precedence keeps declaration order", http.MethodGet, "/api/v1/posts/1", nil, nil, "posts"},
+		{"method beats claim", http.MethodPost, "/api/v1/comments", nil, jwt.MapClaims{"plan": "premium"}, "writes"},
+		{"claim match", http.MethodGet, "/api/v1/comments", nil, jwt.MapClaims{"plan": "premium"}, "premium"},
+		{"claim mismatch", http.MethodGet, "/api/v1/comments", nil, jwt.MapClaims{"plan": "free"}, "default"},
+		{"unauthenticated request skips role and claim policies", http.MethodGet, "/api/v1/comments", nil, nil, "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.roles != nil {
+				c.Set("x_user_roles", tt.roles)
+			}
+			if tt.claims != nil {
+				c.Set("jwt_claims", tt.claims)
+			}
+			if got := m.match(c).Name; got != tt.want {
+				t.Errorf("policy = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyInheritsGlobalRule(t *testing.T) {
+	policies, err := newPolicies(&config.RateLimitConfig{
+		Requests: 100,
+		Window:   time.Minute,
+		Strategy: StrategyTokenBucket,
+		Policies: []config.RateLimitPolicy{
+			{Name: "auth", Routes: []string{"/api/v1/auth/*path"}, Requests: 20},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{Strategy: StrategyTokenBucket, Limit: 20, Window: time.Minute}
+	if policies[0].Rule != want {
+		t.Errorf("rule = %+v, want %+v", policies[0].Rule, want)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name         string
+		globalDryRun bool
+		policyDryRun bool
+		path         string
+		wantRejected bool
+	}{
+		{"enforced", false, false, "/api/v1/posts/1", true},
+		{"global dry run", true, false, "/api/v1/posts/1", false},
+		{"policy dry run", false, true, "/api/v1/posts/1", false},
+		{"policy dry run leaves other routes enforced", false, true, "/api/v1/comments", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMiddleware(NewMemoryStore(), &config.RateLimitConfig{
+				Requests: 1,
+				Window:   time.Minute,
+				Strategy: StrategyFixedWindow,
+				DryRun:   tt.globalDryRun,
+				Policies: []config.RateLimitPolicy{
+					{Name: "posts", Routes: []string{"/api/v1/posts/*path"}, DryRun: tt.policyDryRun},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine := gin.New()
+			engine.Use(m.Handler())
+			engine.GET("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var w *httptest.ResponseRecorder
+			for range 2 {
+				w = httptest.NewRecorder()
+				engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			}
+
+			if tt.wantRejected {
+				if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+					t.Errorf("status = %d, Retry-After %q, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+				}
+				return
+			}
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if h := w.Header().Get("RateLimit-Limit"); h != "" {
+				t.Errorf("RateLimit-Limit = %q, want none in dry run", h)
+			}
+		})
+	}
+}
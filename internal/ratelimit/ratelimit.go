@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Strategies selectable through RateLimitConfig.Strategy
+const (
+	StrategyTokenBucket   = "token-bucket"
+	StrategyFixedWindow   = "fixed-window"
+	StrategySlidingWindow = "sliding-window"
+	StrategyGCRA          = "gcra"
+)
+
+// Rule is a limit of Limit requests per Window enforced with Strategy
+type Rule struct {
+	Strategy string
+	Limit    int
+	Window   time.Duration
+}
+
+func (r Rule) Validate() error {
+	switch r.Strategy {
+	case StrategyTokenBucket, StrategyFixedWindow, StrategySlidingWindow, StrategyGCRA:
+	default:
+		return fmt.Errorf("unknown rate limit strategy %q", r.Strategy)
+	}
+	if r.Limit <= 0 {
+		return fmt.Errorf("rate limit must be positive, got %d", r.Limit)
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("rate limit window must be positive, got %v", r.Window)
+	}
+	return nil
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully restored
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps rate limit state and atomically checks and consumes quota
+type Store interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"api-gateway/internal/config"
+)
+
+// Scripts use the Redis server clock so replicas with skewed clocks agree.
+// All durations passed in and returned are milliseconds.
+
+// slidingWindowScript keeps a sorted set of request times per key
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, now .. '-' .. ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local retry = 0
+if allowed == 0 then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	retry = tonumber(oldest[2]) + window - now
+end
+local newest = redis.call('ZREVRANGE', key, 0, 0, 'WITHSCORES')
+local reset = tonumber(newest[2]) + window - now
+
+return {allowed, limit - count, reset, retry}
+`)
+
+// gcraScript stores the theoretical arrival time of the next request
+var gcraScript = redis.NewScript(`
+local key = KEYS[1]
+local emission = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local tolerance = emission * limit
+
+local tat = tonumber(redis.call('GET', key)) or now
+if tat < now then
+	tat = now
+end
+local new_tat = tat + emission
+local allow_at = new_tat - tolerance
+
+if now < allow_at then
+	return {0, 0, math.ceil(tat - now), math.ceil(allow_at - now)}
+end
+
+redis.call('SET', key, new_tat, 'PX', math.ceil(new_tat - now))
+local remaining = math.floor((tolerance - (new_tat - now)) / emission)
+return {1, remaining, math.ceil(new_tat - now), 0}
+`)
+
+// fixedWindowScript counts requests in a window starting at the first request
+var fixedWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local count = redis.call('INCR', key)
+if count == 1 then
+	redis.call('PEXPIRE', key, ARGV[1])
+end
+local ttl = redis.call('PTTL', key)
+if ttl < 0 then
+	redis.call('PEXPIRE', key, ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisStore is a Store shared by all gateway replicas. Token-bucket rules
+// are enforced with GCRA, which gives the same burst and rate behaviour
+// with a single value per key.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// NewRedisClient creates a client from RedisConfig with short timeouts, so a
+// slow Redis degrades to the local fallback instead of stalling requests
+func NewRedisClient(cfg *config.RedisConfig) (*redis.Client, error) {
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	opts.MaxRetries = cfg.MaxRetries
+	opts.PoolSize = cfg.PoolSize
+	opts.MinIdleConns = cfg.MinIdleConns
+	opts.DialTimeout = time.Second
+	opts.ReadTimeout = 200 * time.Millisecond
+	opts.WriteTimeout = 200 * time.Millisecond
+
+	return redis.NewClient(opts), nil
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if err := rule.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	windowMs := rule.Window.Milliseconds()
+	key = s.prefix + rule.Strategy + ":" + key
+
+	switch rule.Strategy {
+	case StrategyFixedWindow:
+		vals, err := fixedWindowScript.Run(ctx, s.client, []string{key}, windowMs).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		count, reset := int(vals[0]), time.Duration(vals[1])*time.Millisecond
+		res := Result{
+			Allowed:   count <= rule.Limit,
+			Limit:     rule.Limit,
+			Remaining: max(rule.Limit-count, 0),
+			Reset:     reset,
+		}
+		if !res.Allowed {
+			res.RetryAfter = reset
+		}
+		return res, nil
+
+	case StrategySlidingWindow:
+		vals, err := slidingWindowScript.Run(ctx, s.client, []string{key},
+			windowMs, rule.Limit, uuid.NewString()).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return scriptResult(vals, rule), nil
+
+	default:
+		emission := float64(windowMs) / float64(rule.Limit)
+		vals, err := gcraScript.Run(ctx, s.client, []string{key},
+			strconv.FormatFloat(emission, 'f', -1, 64), rule.Limit).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return scriptResult(vals, rule), nil
+	}
+}
+
+// scriptResult decodes {allowed, remaining, reset_ms, retry_ms}
+func scriptResult(vals []int64, rule Rule) Result {
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      rule.Limit,
+		Remaining:  int(vals[1]),
+		Reset:      time.Duration(vals[2]) * time.Millisecond,
+		RetryAfter: time.Duration(vals[3]) * time.Millisecond,
+	}
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis starts an in-process Redis with a controllable clock.
+// advance moves both the TIME seen by the scripts and key expiry.
+func testRedis(t *testing.T) (*miniredis.Miniredis, *RedisStore, func(time.Duration)) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.SetTime(now)
+
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		m.SetTime(now)
+		m.FastForward(d)
+	}
+	return m, NewRedisStore(client, "test:"), advance
+}
+
+type step struct {
+	advance       time.Duration
+	wantAllowed   bool
+	wantRemaining int
+	// wantRetry is checked only for rejected requests
+	wantRetry time.Duration
+}
+
+func runSteps(t *testing.T, store Store, advance func(time.Duration), rule Rule, steps []step) {
+	t.Helper()
+	for i, s := range steps {
+		advance(s.advance)
+		res, err := store.Allow(context.Background(), "client", rule)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if res.Allowed != s.wantAllowed {
+			t.Fatalf("step %d: allowed = %v, want %v", i, res.Allowed, s.wantAllowed)
+		}
+		if res.Allowed && res.Remaining != s.wantRemaining {
+			t.Errorf("step %d: remaining = %d, want %d", i, res.Remaining, s.wantRemaining)
+		}
+		if !res.Allowed && res.RetryAfter != s.wantRetry {
+			t.Errorf("step %d: retry after = %v, want %v", i, res.RetryAfter, s.wantRetry)
+		}
+		if res.Limit != rule.Limit {
+			t.Errorf("step %d: limit = %d, want %d", i, res.Limit, rule.Limit)
+		}
+	}
+}
+
+// The step tables are shared by every store, so switching stores in
+// FallbackStore does not change how a limit behaves
+
+var fixedWindowRule = Rule{Strategy: StrategyFixedWindow, Limit: 3, Window: time.Second}
+
+var fixedWindowSteps = []step{
+	{0, true, 2, 0},
+	{100 * time.Millisecond, true, 1, 0},
+	{100 * time.Millisecond, true, 0, 0},
+	{100 * time.Millisecond, false, 0, 700 * time.Millisecond},
+	// The window started with the first request and has now expired
+	{700 * time.Millisecond, true, 2, 0},
+}
+
+var slidingWindowRule = Rule{Strategy: StrategySlidingWindow, Limit: 2, Window: time.Second}
+
+var slidingWindowSteps = []step{
+	{0, true, 1, 0},
+	{500 * time.Millisecond, true, 0, 0},
+	// The oldest request leaves the window 400ms later
+	{100 * time.Millisecond, false, 0, 400 * time.Millisecond},
+	{401 * time.Millisecond, true, 0, 0},
+	{0, false, 0, 499 * time.Millisecond},
+}
+
+// gcraRule allows one request every 500ms with a burst of two
+func gcraRule(strategy string) Rule {
+	return Rule{Strategy: strategy, Limit: 2, Window: time.Second}
+}
+
+var gcraSteps = []step{
+	{0, true, 1, 0},
+	{0, true, 0, 0},
+	{0, false, 0, 500 * time.Millisecond},
+	{500 * time.Millisecond, true, 0, 0},
+	{time.Second, true, 1, 0},
+}
+
+func TestRedisStoreFixedWindow(t *testing.T) {
+	_, store, advance := testRedis(t)
+	runSteps(t, store, advance, fixedWindowRule, fixedWindowSteps)
+}
+
+func TestRedisStoreSlidingWindow(t *testing.T) {
+	_, store, advance := testRedis(t)
+	runSteps(t, store, advance, slidingWindowRule, slidingWindowSteps)
+}
+
+func TestRedisStoreGCRA(t *testing.T) {
+	for _, strategy := range []string{StrategyGCRA, StrategyTokenBucket} {
+		t.Run(strategy, func(t *testing.T) {
+			_, store, advance := testRedis(t)
+			runSteps(t, store, advance, gcraRule(strategy), gcraSteps)
+		})
+	}
+}
+
+func TestRedisStoreKeysAreIndependent(t *testing.T) {
+	_, store, _ := testRedis(t)
+	rule := Rule{Strategy: StrategyFixedWindow, Limit: 1, Window: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := store.Allow(ctx, "a", rule); !res.Allowed {
+		t.Fatal("first request for a rejected")
+	}
+	if res, _ := store.Allow(ctx, "a", rule); res.Allowed {
+		t.Fatal("second request for a allowed")
+	}
+	if res, _ := store.Allow(ctx, "b", rule); !res.Allowed {
+		t.Fatal("first request for b rejected")
+	}
+}
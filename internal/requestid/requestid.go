@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID between clients, the gateway and upstreams
+const Header = "X-Request-ID"
+
+// maxLength bounds client supplied IDs so they are safe to log and forward
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates a request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a client supplied ID can be reused: non-empty,
+// bounded and limited to visible ASCII characters
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or ""
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
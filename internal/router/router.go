@@ -1 +1,58 @@
 package router
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/proxy"
+)
+
+// RegisterRoutes builds the proxied routes from the configured route table.
+// Routes with Auth set are wrapped in the given auth middleware. The rate
+// limiter, when not nil, runs after auth so it can key on the user ID.
+func RegisterRoutes(router *gin.Engine, routes []config.RouteConfig, p *proxy.ReverseProxy, auth, rateLimit gin.HandlerFunc) {
+	for _, route := range routes {
+		handlers := []gin.HandlerFunc{}
+		if route.Auth {
+			handlers = append(handlers, auth)
+		}
+		if rateLimit != nil {
+			handlers = append(handlers, rateLimit)
+		}
+		handlers = append(handlers, p.Handler(route))
+
+		for _, method := range route.MethodList() {
+			router.Handle(method, route.Path, handlers...)
+		}
+
+		methods := "*"
+		if len(route.Methods) > 0 {
+			methods = strings.Join(route.Methods, ",")
+		}
+		slog.Info("Route registered", "methods", methods, "path", route.Path, "service", route.Service, "auth", route.Auth)
+	}
+}
+
+// AllowedMethods returns a lookup of the methods the route table serves for
+// a concrete request path, used to answer CORS preflights per route
+func AllowedMethods(routes []config.RouteConfig) func(path string) []string {
+	return func(path string) []string {
+		var methods []string
+		seen := make(map[string]bool)
+		for _, route := range routes {
+			if !config.MatchPath(route.Path, path) {
+				continue
+			}
+			for _, m := range route.MethodList() {
+				if !seen[m] {
+					seen[m] = true
+					methods = append(methods, m)
+				}
+			}
+		}
+		return methods
+	}
+}
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/middleware"
+	"api-gateway/internal/proxy"
+)
+
+const testSecret = "test-secret"
+
+// testRoutes mirrors the default route groups: public auth routes, public
+// reads and JWT protected writes
+var testRoutes = []config.RouteConfig{
+	{Path: "/api/v1/auth/*path", Service: "auth"},
+	{Path: "/api/v1/posts", Methods: []string{"GET"}, Service: "post"},
+	{Path: "/api/v1/posts/*path", Methods: []string{"GET"}, Service: "post"},
+	{Path: "/api/v1/comments", Methods: []string{"GET"}, Service: "comment"},
+	{Path: "/api/v1/posts", Methods: []string{"POST"}, Service: "post", Auth: true},
+	{Path: "/api/v1/comments/*path", Methods: []string{"PATCH", "DELETE"}, Service: "comment", Auth: true},
+}
+
+// recorder is an upstream remembering the last request it received
+type recorder struct {
+	mu     sync.Mutex
+	hits   int
+	path   string
+	header http.Header
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hits++
+	r.path = req.URL.Path
+	r.header = req.Header.Clone()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *recorder) last() (int, string, http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hits, r.path, r.header
+}
+
+func newTestGateway(t *testing.T) (*httptest.Server, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	upstream := httptest.NewServer(rec)
+	t.Cleanup(upstream.Close)
+
+	cfg := &config.Config{
+		Proxy:       &config.ProxyConfig{ReservedHeaders: []string{"X-User-ID", "X-Username"}},
+		Concurrency: &config.ConcurrencyConfig{},
+		Services:    make(map[string]*config.ServiceConfig),
+	}
+	for _, name := range []string{"auth", "post", "comment"} {
+		cfg.Services[name] = &config.ServiceConfig{
+			Name:      name,
+			URL:       upstream.URL,
+			Upstreams: []config.UpstreamConfig{{URL: upstream.URL, Weight: 1}},
+		}
+	}
+
+	p, err := proxy.NewReverseProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.StripReservedHeaders(cfg.Proxy.ReservedHeaders))
+	RegisterRoutes(engine, testRoutes, p, middleware.NewJWTMiddleware(testSecret).Handler(), nil)
+
+	gw := httptest.NewServer(engine)
+	t.Cleanup(gw.Close)
+	return gw, rec
+}
+
+func testToken(t *testing.T) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":      "42",
+		"username": "alice",
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	gw, rec := newTestGateway(t)
+	token := testToken(t)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        bool
+		wantStatus   int
+		wantUpstream bool
+		wantUserID   string
+		wantUsername string
+	}{
+		{"auth route is public", http.MethodPost, "/api/v1/auth/login", false, http.StatusOK, true, "", ""},
+		{"auth route ignores tokens", http.MethodPost, "/api/v1/auth/refresh", true, http.StatusOK, true, "", ""},
+		{"public read", http.MethodGet, "/api/v1/posts/7", false, http.StatusOK, true, "", ""},
+		{"public list", http.MethodGet, "/api/v1/comments", false, http.StatusOK, true, "", ""},
+		{"protected write without token", http.MethodPost, "/api/v1/posts", false, http.StatusUnauthorized, false, "", ""},
+		{"protected write with token", http.MethodPost, "/api/v1/posts", true, http.StatusOK, true, "42", "alice"},
+		{"protected catch-all with token", http.MethodDelete, "/api/v1/comments/5", true, http.StatusOK, true, "42", "alice"},
+		{"method outside the route table", http.MethodPut, "/api/v1/posts", true, http.StatusNotFound, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hitsBefore, _, _ := rec.last()
+
+			req, err := http.NewRequest(tt.method, gw.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Spoofed identity must never reach an upstream
+			req.Header.Set("X-User-ID", "1")
+			req.Header.Set("X-Username", "mallory")
+			if tt.token {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			hits, path, header := rec.last()
+			if reached := hits > hitsBefore; reached != tt.wantUpstream {
+				t.Fatalf("upstream reached = %v, want %v", reached, tt.wantUpstream)
+			}
+			if !tt.wantUpstream {
+				return
+			}
+			if path != tt.path {
+				t.Errorf("upstream path = %q, want %q", path, tt.path)
+			}
+			if got := header.Values("X-User-ID"); !equalValues(got, tt.wantUserID) {
+				t.Errorf("upstream X-User-ID = %q, want %q", got, tt.wantUserID)
+			}
+			if got := header.Values("X-Username"); !equalValues(got, tt.wantUsername) {
+				t.Errorf("upstream X-Username = %q, want %q", got, tt.wantUsername)
+			}
+		})
+	}
+}
+
+// equalValues reports whether a header has exactly want, or is absent when want is empty
+func equalValues(got []string, want string) bool {
+	if want == "" {
+		return len(got) == 0
+	}
+	return len(got) == 1 && got[0] == want
+}
@@ -0,0 +1,28 @@
+package tracing
+
+import (
+	"github.com/streadway/amqp"
+)
+
+// AMQPCarrier adapts AMQP message headers to a propagation.TextMapCarrier
+// so trace context travels with published messages
+type AMQPCarrier amqp.Table
+
+func (c AMQPCarrier) Get(key string) string {
+	if v, ok := c[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (c AMQPCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c AMQPCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"api-gateway/internal/config"
+)
+
+const tracerName = "api-gateway"
+
+// Supported values of TRACING_PROVIDER. Jaeger ingests OTLP natively, so
+// "jaeger" is an alias for the OTLP exporter.
+const (
+	ProviderOTLP   = "otlp"
+	ProviderJaeger = "jaeger"
+	ProviderStdout = "stdout"
+	ProviderFile   = "file"
+)
+
+// Tracer returns the gateway tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Init installs the W3C trace context and baggage propagators and, when
+// tracing is enabled, a tracer provider exporting to the configured
+// backend. Propagators are installed even with tracing disabled so incoming
+// trace context still reaches upstreams and queues. The returned function
+// flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !cfg.Metrics.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.AppName),
+		semconv.DeploymentEnvironment(cfg.AppEnv),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Metrics.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg *config.MetricsConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.TracingProvider {
+	case ProviderOTLP, ProviderJaeger:
+		endpoint, err := url.Parse(cfg.TracingEndpoint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid TRACING_ENDPOINT: %w", err)
+		}
+		if endpoint.Path == "" || endpoint.Path == "/" {
+			endpoint.Path = "/v1/traces"
+		}
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint.String()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		return exporter, nil, nil
+
+	case ProviderStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, nil, err
+
+	case ProviderFile:
+		f, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open TRACING_FILE: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing provider %q", cfg.TracingProvider)
+	}
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"api-gateway/internal/config"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs the propagators and a provider recording every span
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := Init(context.Background(), &config.Config{Metrics: &config.MetricsConfig{}}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantName    string
+		wantStatus  codes.Code
+	}{
+		{"continues incoming trace", "/api/v1/posts/1", incomingTraceparent, "GET /api/v1/posts/:id", codes.Unset},
+		{"new trace", "/api/v1/posts/1", "", "GET /api/v1/posts/:id", codes.Unset},
+		{"server error", "/api/v1/posts/fail", "", "GET /api/v1/posts/:id", codes.Error},
+		{"unmatched route", "/unknown", "", "GET", codes.Unset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+
+			var handlerSpan trace.SpanContext
+			engine := gin.New()
+			engine.Use(Middleware())
+			engine.GET("/api/v1/posts/:id", func(c *gin.Context) {
+				handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+				if c.Param("id") == "fail" {
+					c.Status(http.StatusBadGateway)
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+
+			if span.Name() != tt.wantName {
+				t.Errorf("span name = %q, want %q", span.Name(), tt.wantName)
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("span kind = %v, want server", span.SpanKind())
+			}
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("span status = %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+
+			if tt.traceparent != "" {
+				if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+					t.Errorf("trace id = %s, want the incoming trace", got)
+				}
+				if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+					t.Errorf("parent span = %s, want 00f067aa0ba902b7", got)
+				}
+			} else if span.Parent().IsValid() {
+				t.Errorf("span has parent %s, want a root span", span.Parent().SpanID())
+			}
+
+			if tt.path == "/unknown" {
+				if route := attr(span, "http.route"); route.Type() != attribute.INVALID {
+					t.Errorf("unmatched request has http.route %q", route.Emit())
+				}
+				return
+			}
+			if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+				t.Error("handler context does not carry the server span")
+			}
+			if got := attr(span, "http.route").AsString(); got != "/api/v1/posts/:id" {
+				t.Errorf("http.route = %q", got)
+			}
+		})
+	}
+}
+
+func TestAMQPCarrierRoundTrip(t *testing.T) {
+	recordSpans(t)
+
+	ctx, span := Tracer().Start(context.Background(), "publish")
+	defer span.End()
+
+	headers := amqp.Table{"x-existing": "kept"}
+	otel.GetTextMapPropagator().Inject(ctx, AMQPCarrier(headers))
+	if _, ok := headers["traceparent"].(string); !ok {
+		t.Fatalf("headers = %v, want a traceparent", headers)
+	}
+	if headers["x-existing"] != "kept" {
+		t.Error("existing header overwritten")
+	}
+
+	got := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), AMQPCarrier(headers)))
+	if got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("extracted %s/%s, want %s/%s", got.TraceID(), got.SpanID(),
+			span.SpanContext().TraceID(), span.SpanContext().SpanID())
+	}
+
+	// Non-string header values are ignored rather than panicking
+	if v := AMQPCarrier(amqp.Table{"traceparent": int64(1)}).Get("traceparent"); v != "" {
+		t.Errorf("Get on non-string = %q, want empty", v)
+	}
+}